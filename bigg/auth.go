@@ -102,7 +102,7 @@ func (a *Client) NewYoutubeService() (*Youtube, error) {
 		return nil, errors.New("Client not authorized")
 	}
 	svc, err := youtube.NewService(a.context, option.WithHTTPClient(a.httpClient))
-	return &Youtube{svc, 0}, err
+	return &Youtube{svc: svc, retryPolicy: DefaultRetryPolicy}, err
 }
 
 //
@@ -0,0 +1,139 @@
+package bigg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// etagMaxAge is how long a page not requested anymore is kept in an ETag store.
+const etagMaxAge = 30 * 24 * time.Hour
+
+// ETagStore remembers the ETag and the content of list responses by request
+// (endpoint, params and page token), to send conditional requests (If-None-Match)
+// on the next run: an unchanged page is answered with a 304 (Not Modified) and
+// replayed from the store. It's persisted as a JSON file, one for each account.
+type ETagStore struct {
+	file string
+
+	mu    sync.Mutex
+	dirty bool
+	// request key -> page
+	Pages map[string]etagPage `json:"pages"`
+}
+
+type etagPage struct {
+	ETag string          `json:"etag"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// LoadETagStore reads an ETag store file. A missing or unreadable (eg. corrupted) file results
+// in an empty store: the pages are only requested again, and the next Save replaces the file.
+func LoadETagStore(file string) (*ETagStore, error) {
+	e := &ETagStore{file: file, Pages: map[string]etagPage{}}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return e, nil
+	}
+	if err := json.Unmarshal(data, e); err != nil || e.Pages == nil {
+		// the whole file is dropped, its pages may be partially decoded
+		e.Pages = map[string]etagPage{}
+		e.dirty = true
+	}
+	return e, nil
+}
+
+// Save writes the store file, if anything changed since it was loaded. The file is replaced
+// atomically: an execution dying while saving doesn't leave a truncated store.
+// Pages not requested in the last 30 days are dropped.
+func (e *ETagStore) Save() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.dirty {
+		return nil
+	}
+	for key, p := range e.Pages {
+		if time.Since(p.Time) > etagMaxAge {
+			delete(e.Pages, key)
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("etag store write error: %w", err)
+	}
+	if err := writeFileAtomic(e.file, data, 0600); err != nil {
+		return fmt.Errorf("etag store write error: %w", err)
+	}
+	e.dirty = false
+	return nil
+}
+
+func (e *ETagStore) get(key string) (etagPage, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.Pages[key]
+	return p, ok
+}
+
+func (e *ETagStore) put(key string, p etagPage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Pages[key] = p
+	e.dirty = true
+}
+
+// SetETagStore enables the conditional requests of the list calls
+// (SubscriptionsList, PlaylistsList and PlaylistItemsList).
+func (s *Youtube) SetETagStore(e *ETagStore) {
+	s.etags = e
+}
+
+// IsNotModified returns true if err is a 304 response of the API.
+func IsNotModified(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotModified
+}
+
+// doPage is do for a page of a list call. With an ETag store, the ETag of the same page
+// (by method, params and page token) is sent with ifNoneMatch and, on a 304, the stored page
+// is returned. The quota cost is charged anyway.
+func doPage[T any](ctx context.Context, s *Youtube, method, params, pageToken string, cost uint32,
+	ifNoneMatch func(string), call func(...googleapi.CallOption) (T, error), etag func(T) string) (T, error) {
+	if s.etags == nil {
+		return do(ctx, s, method, cost, call)
+	}
+	key := fmt.Sprintf("%s?%s&pageToken=%s", method, params, pageToken)
+	stored, ok := s.etags.get(key)
+	// the call is reused for all pages, its ETag must be reset
+	ifNoneMatch(stored.ETag)
+	defer ifNoneMatch("")
+	res, err := do(ctx, s, method, cost, call)
+	if ok && IsNotModified(err) {
+		var out T
+		if err := json.Unmarshal(stored.Data, &out); err == nil {
+			stored.Time = time.Now()
+			s.etags.put(key, stored)
+			return out, nil
+		}
+		// unreadable page, requested again without the ETag
+		ifNoneMatch("")
+		res, err = do(ctx, s, method, cost, call)
+	}
+	if err != nil {
+		return res, err
+	}
+	if tag := etag(res); tag != "" {
+		if data, err := json.Marshal(res); err == nil {
+			s.etags.put(key, etagPage{ETag: tag, Time: time.Now(), Data: data})
+		}
+	}
+	return res, nil
+}
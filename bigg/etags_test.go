@@ -0,0 +1,124 @@
+package bigg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestETagPlaylistItemsList(t *testing.T) {
+	t.Run("replays the unchanged pages of the previous run", func(t *testing.T) {
+		var mu sync.Mutex
+		conditional := []string{}
+		notModified := 0
+		// the second page changes after the first run
+		pages := map[string]struct{ etag, body string }{
+			"":   {`"E1"`, `{"etag":"\"E1\"","items":[{"id":"I1"}],"nextPageToken":"P2"}`},
+			"P2": {`"E2"`, `{"etag":"\"E2\"","items":[{"id":"I2"}]}`},
+		}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			page := pages[r.URL.Query().Get("pageToken")]
+			conditional = append(conditional, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == page.etag {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			fmt.Fprint(w, page.body)
+		}))
+		defer ts.Close()
+
+		file := filepath.Join(t.TempDir(), "token.etags")
+		list := func() []string {
+			s := newTestYoutube(t, ts)
+			store, err := LoadETagStore(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.SetETagStore(store)
+			out := make(chan *PlaylistItem, 10)
+			err = s.PlaylistItemsList(context.Background(), "PL",
+				func(*PlaylistItem) (bool, error) { return true, nil }, out)
+			close(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for i := range out {
+				got = append(got, i.Id)
+			}
+			return got
+		}
+
+		if diff := cmp.Diff([]string{"I1", "I2"}, list()); diff != "" {
+			t.Errorf("first run mismatch (-want +got):\n%s", diff)
+		}
+		mu.Lock()
+		pages["P2"] = struct{ etag, body string }{`"E3"`, `{"etag":"\"E3\"","items":[{"id":"I3"}]}`}
+		mu.Unlock()
+		if diff := cmp.Diff([]string{"I1", "I3"}, list()); diff != "" {
+			t.Errorf("second run mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"I1", "I3"}, list()); diff != "" {
+			t.Errorf("third run mismatch (-want +got):\n%s", diff)
+		}
+
+		want := []string{"", "", `"E1"`, `"E2"`, `"E1"`, `"E3"`}
+		if diff := cmp.Diff(want, conditional); diff != "" {
+			t.Errorf("If-None-Match mismatch (-want +got):\n%s", diff)
+		}
+		if notModified != 3 {
+			t.Errorf("want 3 not modified pages, got: %d", notModified)
+		}
+	})
+}
+
+func TestETagStoreFile(t *testing.T) {
+	t.Run("an unreadable store is empty and replaced on save", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "token.etags")
+		if err := os.WriteFile(file, []byte(`{"pages":{"k":{"etag":"E`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		store, err := LoadETagStore(file)
+		if err != nil {
+			t.Fatalf("want no error, got: %v", err)
+		}
+		if len(store.Pages) != 0 {
+			t.Errorf("want an empty store, got: %v", store.Pages)
+		}
+		if err := store.Save(); err != nil {
+			t.Fatal(err)
+		}
+		store, err = LoadETagStore(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.put("k", etagPage{ETag: "E1", Time: time.Now(), Data: []byte(`{}`)})
+		if err := store.Save(); err != nil {
+			t.Fatal(err)
+		}
+		store, err = LoadETagStore(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := store.get("k"); !ok || p.ETag != "E1" {
+			t.Errorf("want the saved page, got: %v", store.Pages)
+		}
+		if leftovers, _ := filepath.Glob(file + ".*"); len(leftovers) != 0 {
+			t.Errorf("want no temporary files, got: %s", leftovers)
+		}
+	})
+}
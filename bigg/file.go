@@ -0,0 +1,79 @@
+package bigg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockTimeout is how long lockFile waits for a lock held by another execution.
+	lockTimeout = 10 * time.Second
+	// lockStale is the age of a lock left by an execution that crashed holding it.
+	lockStale = time.Minute
+	// lockRetry is the wait between two attempts to take a lock.
+	lockRetry = 20 * time.Millisecond
+)
+
+// writeFileAtomic writes data to a temporary file in the directory of file, then renames it:
+// a crash never leaves a truncated file, and readers get either the previous or the new content.
+// The directory is created if missing.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// lockFile takes an exclusive lock on file across executions, creating the lock file
+// file.lock next to it, and returns the function releasing it.
+// It waits up to lockTimeout for another execution to release the lock; a lock older
+// than lockStale is left by a crashed execution and is taken over.
+func lockFile(file string) (func(), error) {
+	lock := file + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0700); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another execution (remove %s if none is running)", file, lock)
+		}
+		time.Sleep(lockRetry)
+	}
+}
@@ -0,0 +1,148 @@
+package bigg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	_ "time/tzdata" // quota days are in Pacific Time, don't rely on the system tz database
+)
+
+// DAILY_QUOTA is the default daily quota of a GCloud project.
+const DAILY_QUOTA uint32 = 10000
+
+// ledgerDays is the number of quota days (today included) kept in a ledger file.
+const ledgerDays = 7
+
+var quotaLocation = mustLoadLocation("America/Los_Angeles")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// QuotaDay returns the quota day (YYYY-MM-DD) of t.
+// YouTube quota resets at midnight Pacific Time.
+func QuotaDay(t time.Time) string {
+	return t.In(quotaLocation).Format("2006-01-02")
+}
+
+// Ledger keeps track of the quota spent across executions, by client ID,
+// quota day and api method. It's persisted as a JSON file.
+type Ledger struct {
+	file string
+	// client id -> quota day -> api method -> units
+	Clients map[string]map[string]map[string]uint32 `json:"clients"`
+}
+
+// DefaultLedgerFile returns the ledger file in the user config directory,
+// so that every execution records to the same ledger whatever the working directory.
+func DefaultLedgerFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "youtubetoolkit", "quota-ledger.json"), nil
+}
+
+// LoadLedger reads a ledger file. A missing file results in an empty ledger.
+func LoadLedger(file string) (*Ledger, error) {
+	l := &Ledger{file: file, Clients: map[string]map[string]map[string]uint32{}}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("ledger read error: %w", err)
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("ledger file %s corrupted: %w", file, err)
+	}
+	if l.Clients == nil {
+		l.Clients = map[string]map[string]map[string]uint32{}
+	}
+	return l, nil
+}
+
+// Add records the costs (by api method) spent by clientID in the quota day.
+func (l *Ledger) Add(clientID, day string, costs map[string]uint32) {
+	days, ok := l.Clients[clientID]
+	if !ok {
+		days = map[string]map[string]uint32{}
+		l.Clients[clientID] = days
+	}
+	methods, ok := days[day]
+	if !ok {
+		methods = map[string]uint32{}
+		days[day] = methods
+	}
+	for m, c := range costs {
+		methods[m] += c
+	}
+}
+
+// Used returns the quota spent by clientID in the quota day, in total and by api method.
+func (l *Ledger) Used(clientID, day string) (uint32, map[string]uint32) {
+	var total uint32
+	methods := map[string]uint32{}
+	for m, c := range l.Clients[clientID][day] {
+		methods[m] = c
+		total += c
+	}
+	return total, methods
+}
+
+// Remaining returns the quota left to clientID in the quota day, out of a daily limit.
+func (l *Ledger) Remaining(clientID, day string, limit uint32) uint32 {
+	used, _ := l.Used(clientID, day)
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// Save writes the ledger file, dropping the quota days older than a week.
+// The file is replaced atomically, but concurrent updates need UpdateLedger.
+func (l *Ledger) Save() error {
+	// quota days are YYYY-MM-DD, they sort as strings
+	oldest := QuotaDay(time.Now().In(quotaLocation).AddDate(0, 0, 1-ledgerDays))
+	for client, days := range l.Clients {
+		for d := range days {
+			if d < oldest {
+				delete(days, d)
+			}
+		}
+		if len(days) == 0 {
+			delete(l.Clients, client)
+		}
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ledger write error: %w", err)
+	}
+	if err := writeFileAtomic(l.file, data, 0600); err != nil {
+		return fmt.Errorf("ledger write error: %w", err)
+	}
+	return nil
+}
+
+// UpdateLedger reloads the ledger file, applies update and saves it, holding a lock
+// on the file: overlapping executions (like cron jobs) don't lose each other's usage.
+func UpdateLedger(file string, update func(*Ledger)) error {
+	unlock, err := lockFile(file)
+	if err != nil {
+		return fmt.Errorf("ledger lock error: %w", err)
+	}
+	defer unlock()
+	l, err := LoadLedger(file)
+	if err != nil {
+		return err
+	}
+	update(l)
+	return l.Save()
+}
@@ -0,0 +1,190 @@
+package bigg
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuotaDay(t *testing.T) {
+	tests := []struct {
+		time string
+		want string
+	}{
+		// midnight Pacific Time is 08:00 UTC in winter (PST)...
+		{"2022-01-15T07:59:59Z", "2022-01-14"},
+		{"2022-01-15T08:00:00Z", "2022-01-15"},
+		// ...and 07:00 UTC in summer (PDT)
+		{"2022-07-15T06:59:59Z", "2022-07-14"},
+		{"2022-07-15T07:00:00Z", "2022-07-15"},
+		// the zone of the time doesn't matter
+		{"2022-01-15T09:00:00+02:00", "2022-01-14"},
+		{"2022-01-15T00:30:00-08:00", "2022-01-15"},
+	}
+	for _, tt := range tests {
+		tm, err := time.Parse(time.RFC3339, tt.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := QuotaDay(tm); got != tt.want {
+			t.Errorf("QuotaDay(%s): want %s, got %s", tt.time, tt.want, got)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	t.Run("used and remaining quota", func(t *testing.T) {
+		l, err := LoadLedger(filepath.Join(t.TempDir(), "ledger.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Add("C1", "2022-01-15", map[string]uint32{"youtube.playlistItems.list": 3, "youtube.playlistItems.insert": 100})
+		l.Add("C1", "2022-01-15", map[string]uint32{"youtube.playlistItems.insert": 50})
+		l.Add("C1", "2022-01-14", map[string]uint32{"youtube.playlistItems.insert": 9000})
+		l.Add("C2", "2022-01-15", map[string]uint32{"youtube.playlistItems.insert": 9000})
+
+		used, methods := l.Used("C1", "2022-01-15")
+		if used != 153 {
+			t.Errorf("want 153 used, got: %d", used)
+		}
+		want := map[string]uint32{"youtube.playlistItems.list": 3, "youtube.playlistItems.insert": 150}
+		if diff := cmp.Diff(want, methods); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if got := l.Remaining("C1", "2022-01-15", DAILY_QUOTA); got != DAILY_QUOTA-153 {
+			t.Errorf("want %d remaining, got: %d", DAILY_QUOTA-153, got)
+		}
+		if got := l.Remaining("C1", "2022-01-15", 100); got != 0 {
+			t.Errorf("want nothing remaining over the limit, got: %d", got)
+		}
+		if used, _ := l.Used("C3", "2022-01-15"); used != 0 {
+			t.Errorf("want nothing used by an unknown client, got: %d", used)
+		}
+		if got := l.Remaining("C3", "2022-01-15", DAILY_QUOTA); got != DAILY_QUOTA {
+			t.Errorf("want the whole quota remaining, got: %d", got)
+		}
+	})
+
+	t.Run("load, add and save round trip", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "youtubetoolkit", "ledger.json")
+		today := QuotaDay(time.Now())
+		for i := 0; i < 2; i++ {
+			l, err := LoadLedger(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Add("C1", today, map[string]uint32{"youtube.subscriptions.insert": 50})
+			if err := l.Save(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		l, err := LoadLedger(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used, methods := l.Used("C1", today)
+		if used != 100 || methods["youtube.subscriptions.insert"] != 100 {
+			t.Errorf("want 100 units of subscriptions.insert, got: %d %v", used, methods)
+		}
+		if leftovers, _ := filepath.Glob(file + ".*"); len(leftovers) != 0 {
+			t.Errorf("want no temporary files, got: %s", leftovers)
+		}
+	})
+
+	t.Run("drops the quota days older than a week", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "ledger.json")
+		l, err := LoadLedger(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now().In(quotaLocation)
+		days := []string{}
+		for i := 0; i < 10; i++ {
+			day := QuotaDay(now.AddDate(0, 0, -i))
+			days = append(days, day)
+			l.Add("C1", day, map[string]uint32{"youtube.videos.list": 1})
+		}
+		l.Add("C2", days[9], map[string]uint32{"youtube.videos.list": 1})
+		if err := l.Save(); err != nil {
+			t.Fatal(err)
+		}
+		l, err = LoadLedger(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, day := range days {
+			used, _ := l.Used("C1", day)
+			if kept := i < 7; kept != (used == 1) {
+				t.Errorf("day %s (%d days ago): want kept %v, got used %d", day, i, kept, used)
+			}
+		}
+		if _, ok := l.Clients["C2"]; ok {
+			t.Errorf("want client C2 dropped, got: %v", l.Clients["C2"])
+		}
+	})
+
+	t.Run("a missing file is an empty ledger, a corrupted one an error", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := LoadLedger(filepath.Join(dir, "missing.json"))
+		if err != nil || len(l.Clients) != 0 {
+			t.Errorf("want an empty ledger, got: %v %v", l, err)
+		}
+		file := filepath.Join(dir, "corrupted.json")
+		if err := os.WriteFile(file, []byte(`{"clients":`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLedger(file); err == nil {
+			t.Error("want an error")
+		}
+	})
+
+	t.Run("concurrent updates don't lose usage", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "ledger.json")
+		today := QuotaDay(time.Now())
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := UpdateLedger(file, func(l *Ledger) {
+					l.Add("C1", today, map[string]uint32{"youtube.videos.list": 1})
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		l, err := LoadLedger(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used, _ := l.Used("C1", today); used != 20 {
+			t.Errorf("want 20 units used, got: %d", used)
+		}
+		if _, err := os.Stat(file + ".lock"); !os.IsNotExist(err) {
+			t.Errorf("want the lock released, got: %v", err)
+		}
+	})
+
+	t.Run("takes over a stale lock", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "ledger.json")
+		if err := os.WriteFile(file+".lock", nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-2 * lockStale)
+		if err := os.Chtimes(file+".lock", old, old); err != nil {
+			t.Fatal(err)
+		}
+		err := UpdateLedger(file, func(l *Ledger) {
+			l.Add("C1", QuotaDay(time.Now()), map[string]uint32{"youtube.videos.list": 1})
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package bigg
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy configures how API calls failing with a transient error
+// (5xx responses, rate limits, network errors) are retried.
+// Delays grow exponentially from BaseDelay up to MaxDelay, with jitter.
+// A Retry-After response header is always respected.
+type RetryPolicy struct {
+	// MaxRetries is the max number of retries of a single call (zero disables retries)
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  1 * time.Second,
+	MaxDelay:   32 * time.Second,
+}
+
+func (s *Youtube) SetRetryPolicy(p RetryPolicy) {
+	s.retryPolicy = p
+}
+
+// retry runs an API call, charging its quota cost on each attempt, until it succeeds,
+// fails with a non retryable error, runs out of retries or ctx is done.
+func (s *Youtube) retry(ctx context.Context, method string, cost uint32, call func() error) error {
+	// inserts are not idempotent: they are retried only when the request
+	// was surely rejected before being processed
+	idempotent := !strings.HasSuffix(method, ".insert")
+	for attempt := 0; ; attempt++ {
+		// a call of a canceled flow (eg. the next page) is not sent nor charged
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.addcost(method, cost)
+		err := call()
+		if err == nil || attempt >= s.retryPolicy.MaxRetries || !retryable(err, idempotent) {
+			return err
+		}
+		select {
+		case <-time.After(retryDelay(s.retryPolicy, attempt, err)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// do is retry for API calls returning a value.
+func do[T any](ctx context.Context, s *Youtube, method string, cost uint32, call func(...googleapi.CallOption) (T, error)) (T, error) {
+	var out T
+	err := s.retry(ctx, method, cost, func() (err error) {
+		out, err = call()
+		return err
+	})
+	return out, err
+}
+
+// Error reasons (https://developers.google.com/youtube/v3/docs/errors)
+var (
+	rateLimitReasons = map[string]bool{
+		"rateLimitExceeded":     true,
+		"userRateLimitExceeded": true,
+	}
+	serverErrorReasons = map[string]bool{
+		"backendError":  true,
+		"internalError": true,
+	}
+	fatalReasons = map[string]bool{
+		"quotaExceeded":           true,
+		"dailyLimitExceeded":      true,
+		"forbidden":               true,
+		"insufficientPermissions": true,
+	}
+)
+
+// retryable returns true if err is worth a retry.
+// Non idempotent calls are retried only on rate limits and 503s.
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		var nerr net.Error
+		return idempotent && errors.As(err, &nerr)
+	}
+	for _, e := range gerr.Errors {
+		switch {
+		case fatalReasons[e.Reason]:
+			return false
+		case rateLimitReasons[e.Reason]:
+			return true
+		case serverErrorReasons[e.Reason]:
+			return idempotent
+		}
+	}
+	switch gerr.Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// IsNotFound returns true if err is a 404 response of the API (eg. playlistNotFound).
+func IsNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
+
+// retryDelay returns the wait before the next attempt: the Retry-After
+// header value if present, otherwise an exponential backoff with jitter.
+func retryDelay(p RetryPolicy, attempt int, err error) time.Duration {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		if d, ok := parseRetryAfter(gerr.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	d := p.BaseDelay << attempt
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	// "equal jitter": half fixed, half random
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header (seconds or http date).
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,173 @@
+package bigg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryPlaylistItemsList(t *testing.T) {
+	t.Run("retries a failed page with the same page token", func(t *testing.T) {
+		var mu sync.Mutex
+		tokens := []string{}
+		failures := 2
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			token := r.URL.Query().Get("pageToken")
+			tokens = append(tokens, token)
+			if token == "P2" && failures > 0 {
+				failures--
+				writeError(w, http.StatusServiceUnavailable, "backendError")
+				return
+			}
+			switch token {
+			case "":
+				fmt.Fprint(w, `{"items":[{"id":"I1","snippet":{"title":"T1"}}],"nextPageToken":"P2"}`)
+			case "P2":
+				fmt.Fprint(w, `{"items":[{"id":"I2","snippet":{"title":"T2"}}]}`)
+			}
+		}))
+		defer ts.Close()
+		s := newTestYoutube(t, ts)
+
+		out := make(chan *PlaylistItem, 10)
+		err := s.PlaylistItemsList(context.Background(), "PL",
+			func(*PlaylistItem) (bool, error) { return true, nil }, out)
+		close(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for i := range out {
+			got = append(got, i.Id)
+		}
+		if diff := cmp.Diff([]string{"I1", "I2"}, got); diff != "" {
+			t.Errorf("items mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"", "P2", "P2", "P2"}, tokens); diff != "" {
+			t.Errorf("page tokens mismatch (-want +got):\n%s", diff)
+		}
+		if got := s.GetCost(); got != 4 {
+			t.Errorf("want cost 4 (one for each attempt), got: %d", got)
+		}
+	})
+
+	t.Run("doesn't retry quotaExceeded", func(t *testing.T) {
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			writeError(w, http.StatusForbidden, "quotaExceeded")
+		}))
+		defer ts.Close()
+		s := newTestYoutube(t, ts)
+
+		out := make(chan *PlaylistItem, 10)
+		err := s.PlaylistItemsList(context.Background(), "PL",
+			func(*PlaylistItem) (bool, error) { return true, nil }, out)
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != http.StatusForbidden {
+			t.Errorf("want a 403 googleapi.Error, got: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("want 1 call, got: %d", calls)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			writeError(w, http.StatusTooManyRequests, "rateLimitExceeded")
+		}))
+		defer ts.Close()
+		s := newTestYoutube(t, ts)
+
+		_, err := s.GetChannelInfo(context.Background(), "CH")
+		if err == nil {
+			t.Error("want error, got nil")
+		}
+		if calls != fastRetries.MaxRetries+1 {
+			t.Errorf("want %d calls, got: %d", fastRetries.MaxRetries+1, calls)
+		}
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{"500 list", &googleapi.Error{Code: 500}, true, true},
+		{"500 insert", &googleapi.Error{Code: 500}, false, false},
+		{"503 insert", &googleapi.Error{Code: 503}, false, true},
+		{"rate limit insert", apiError(403, "rateLimitExceeded"), false, true},
+		{"quota exceeded", apiError(403, "quotaExceeded"), true, false},
+		{"forbidden", apiError(403, "forbidden"), true, false},
+		{"not found", apiError(404, "playlistNotFound"), true, false},
+		{"canceled", context.Canceled, true, false},
+		{"wrapped", fmt.Errorf("list error: %w", &googleapi.Error{Code: 502}), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err, tt.idempotent); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Run("respects Retry-After", func(t *testing.T) {
+		err := &googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"7"}}}
+		if got := retryDelay(fastRetries, 0, err); got != 7*time.Second {
+			t.Errorf("want 7s, got %v", got)
+		}
+	})
+	t.Run("backoff is capped", func(t *testing.T) {
+		p := RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+		for attempt := 0; attempt < 10; attempt++ {
+			got := retryDelay(p, attempt, &googleapi.Error{Code: 503})
+			if got > p.MaxDelay {
+				t.Errorf("attempt %d: delay %v exceeds max %v", attempt, got, p.MaxDelay)
+			}
+		}
+	})
+}
+
+//
+// support functions
+//
+
+func newTestYoutube(t *testing.T, ts *httptest.Server) *Youtube {
+	svc, err := youtube.NewService(context.Background(),
+		option.WithEndpoint(ts.URL), option.WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Youtube{svc: svc, retryPolicy: fastRetries}
+}
+
+func writeError(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s","errors":[{"reason":"%s"}]}}`, code, reason, reason)
+}
+
+func apiError(code int, reason string) *googleapi.Error {
+	return &googleapi.Error{Code: code, Errors: []googleapi.ErrorItem{{Reason: reason}}}
+}
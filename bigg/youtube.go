@@ -1,15 +1,26 @@
 package bigg
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"google.golang.org/api/youtube/v3"
 )
 
 type Youtube struct {
-	svc  *youtube.Service
-	cost uint32
+	svc         *youtube.Service
+	cost        uint32
+	retryPolicy RetryPolicy
+	etags       *ETagStore
+
+	// quota cost by api method (eg. "playlistItems.list")
+	costsMu sync.Mutex
+	costs   map[string]uint32
 }
 
 type Sub struct {
@@ -28,37 +39,211 @@ type Channel struct {
 	*youtube.Channel
 }
 
+type Video struct {
+	*youtube.Video
+}
+
+// PlaylistMetadata are the editable properties of a playlist (see PlaylistInsert and PlaylistUpdate).
+type PlaylistMetadata struct {
+	Title       string
+	Description string
+	Tags        []string
+	// DefaultLanguage is a BCP-47 language code (eg. "en")
+	DefaultLanguage string
+	// PrivacyStatus is "public", "unlisted" or "private"
+	PrivacyStatus string
+}
+
+// PlaylistMetadataOf returns the metadata of a playlist with the "snippet" and "status" properties.
+func PlaylistMetadataOf(pl *Playlist) PlaylistMetadata {
+	m := PlaylistMetadata{}
+	if pl.Snippet != nil {
+		m.Title = pl.Snippet.Title
+		m.Description = pl.Snippet.Description
+		m.Tags = pl.Snippet.Tags
+		m.DefaultLanguage = pl.Snippet.DefaultLanguage
+	}
+	if pl.Status != nil {
+		m.PrivacyStatus = pl.Status.PrivacyStatus
+	}
+	return m
+}
+
+func (m PlaylistMetadata) playlist() *youtube.Playlist {
+	return &youtube.Playlist{
+		Snippet: &youtube.PlaylistSnippet{
+			Title:           m.Title,
+			Description:     m.Description,
+			Tags:            m.Tags,
+			DefaultLanguage: m.DefaultLanguage,
+		},
+		Status: &youtube.PlaylistStatus{
+			PrivacyStatus: m.PrivacyStatus,
+		},
+	}
+}
+
 const ISO8601_LAYOUT string = "2006-01-02T15:04:05Z0700"
 
-func (s *Youtube) addcost(q uint32) {
+// ParseDuration parses an ISO 8601 duration, as the ones of the videos (eg. "PT1H2M3S").
+// Years and months are not supported.
+func ParseDuration(s string) (time.Duration, error) {
+	if !strings.HasPrefix(s, "P") || len(s) == 1 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	rest := s[1:]
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var d time.Duration
+	for rest != "" {
+		if rest[0] == 'T' {
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			rest = rest[1:]
+			continue
+		}
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		unit, ok := units[rest[i]]
+		if err != nil || !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n * float64(unit))
+		rest = rest[i+1:]
+	}
+	return d, nil
+}
+
+// GCloud quota impact of the API calls made by this package.
+// See https://developers.google.com/youtube/v3/determine_quota_cost
+const (
+	QUOTA_COST_LIST   uint32 = 1
+	QUOTA_COST_INSERT uint32 = 50
+	QUOTA_COST_DELETE uint32 = 50
+	QUOTA_COST_UPDATE uint32 = 50
+	// SubscriptionDelete needs a list call to find the subscription id
+	QUOTA_COST_SUBSCRIPTION_DELETE uint32 = QUOTA_COST_LIST + QUOTA_COST_DELETE
+)
+
+// MAX_BATCH_IDS is the max number of IDs of a single list call (eg. ChannelsList).
+const MAX_BATCH_IDS = 50
+
+func (s *Youtube) addcost(method string, q uint32) {
 	atomic.AddUint32(&s.cost, q)
+	s.costsMu.Lock()
+	defer s.costsMu.Unlock()
+	if s.costs == nil {
+		s.costs = map[string]uint32{}
+	}
+	s.costs[method] += q
 }
 
 func (s *Youtube) GetCost() uint32 {
 	return atomic.LoadUint32(&s.cost)
 }
 
+// GetCostByMethod returns the quota cost split by api method (eg. "subscriptions.insert").
+func (s *Youtube) GetCostByMethod() map[string]uint32 {
+	s.costsMu.Lock()
+	defer s.costsMu.Unlock()
+	out := make(map[string]uint32, len(s.costs))
+	for k, v := range s.costs {
+		out[k] = v
+	}
+	return out
+}
+
+// pageToken returns the page token of the pagination loops of the list calls
+// ("-" is the first page).
+func pageToken(t string) string {
+	if t == "-" {
+		return ""
+	}
+	return t
+}
+
+// MAX_PAGE_SIZE is the max number of items of a page of a list call (eg. PlaylistItemsList).
+const MAX_PAGE_SIZE = 50
+
+type listLimitKey struct{}
+
+// WithListLimit returns a context making the list calls (SubscriptionsList, PlaylistsList
+// and PlaylistItemsList) stop after n items: the last page requests only the items still
+// needed and no more pages are loaded.
+func WithListLimit(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, listLimitKey{}, n)
+}
+
+// ListLimit returns the number of items set by WithListLimit, if any.
+func ListLimit(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(listLimitKey{}).(int)
+	return n, ok && n > 0
+}
+
+// listCounter counts the items of a list call against its ListLimit.
+type listCounter struct {
+	left    int
+	limited bool
+}
+
+func newListCounter(ctx context.Context) *listCounter {
+	n, ok := ListLimit(ctx)
+	return &listCounter{left: n, limited: ok}
+}
+
+// pageSize returns the maxResults of the next page.
+func (c *listCounter) pageSize() int64 {
+	if c.limited && c.left < MAX_PAGE_SIZE {
+		return int64(c.left)
+	}
+	return MAX_PAGE_SIZE
+}
+
+// params returns the params of the next page (see doPage): a smaller page is another request.
+func (c *listCounter) params(p string) string {
+	if size := c.pageSize(); size != MAX_PAGE_SIZE {
+		return fmt.Sprintf("%s&maxResults=%d", p, size)
+	}
+	return p
+}
+
+// sent counts an item sent and returns true once the limit is reached.
+func (c *listCounter) sent() bool {
+	c.left--
+	return c.limited && c.left <= 0
+}
+
 //
 // SUBSCRIPTIONS
 //
 
 // SubscriptionsList sends to out all the user subscriptions.
 // Items will contain only the "snippet" resource property (https://developers.google.com/youtube/v3/docs/subscriptions#snippet).
-// The GCloud quota impact is 1 unit every 50 items fetched.
-func (s *Youtube) SubscriptionsList(out chan<- *Sub) error {
+// The GCloud quota impact is 1 unit every 50 items fetched, it stops early with WithListLimit.
+func (s *Youtube) SubscriptionsList(ctx context.Context, out chan<- *Sub) error {
 	call := s.svc.Subscriptions.List([]string{"snippet"})
 	call.Mine(true)
-	call.MaxResults(50)
 	call.Order("alphabetical")
+	call.Context(ctx)
+	count := newListCounter(ctx)
 	t := "-"
 	for t != "" {
-		s.addcost(1)
-		r, err := call.Do()
+		call.MaxResults(count.pageSize())
+		r, err := doPage(ctx, s, "subscriptions.list", count.params("part=snippet&mine=true&order=alphabetical"), pageToken(t), QUOTA_COST_LIST,
+			func(e string) { call.IfNoneMatch(e) }, call.Do, func(r *youtube.SubscriptionListResponse) string { return r.Etag })
 		if err != nil {
 			return fmt.Errorf("subs list error (page %s): %w", t, err)
 		}
 		for _, v := range r.Items {
-			out <- &Sub{v}
+			select {
+			case out <- &Sub{v}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if count.sent() {
+				return nil
+			}
 		}
 		t = r.NextPageToken
 		call.PageToken(t)
@@ -68,7 +253,7 @@ func (s *Youtube) SubscriptionsList(out chan<- *Sub) error {
 
 // SubscriptionInsert adds a subscription for the authenticated user's channel.
 // The GCloud quota impact is 50 units.
-func (s *Youtube) SubscriptionInsert(channelId string) (*Sub, error) {
+func (s *Youtube) SubscriptionInsert(ctx context.Context, channelId string) (*Sub, error) {
 	sub := &youtube.Subscription{
 		Kind: "youtube#subscription",
 		Snippet: &youtube.SubscriptionSnippet{
@@ -79,22 +264,22 @@ func (s *Youtube) SubscriptionInsert(channelId string) (*Sub, error) {
 		},
 	}
 	call := s.svc.Subscriptions.Insert([]string{"snippet"}, sub)
-	s.addcost(50)
-	r, err := call.Do()
+	call.Context(ctx)
+	r, err := do(ctx, s, "subscriptions.insert", QUOTA_COST_INSERT, call.Do)
 	return &Sub{r}, err
 }
 
 // SubscriptionDelete delete a channel from subscriptions for the authenticated user's channel.
 // This command will results in two api requests (subscriptionId from the channelId and the delete op).
 // The GCloud quota impact is 51 units.
-func (s *Youtube) SubscriptionDelete(channelId string) error {
+func (s *Youtube) SubscriptionDelete(ctx context.Context, channelId string) error {
 	// find the sub id from channel id
 	lcall := s.svc.Subscriptions.List([]string{"snippet"})
 	lcall.Mine(true)
 	lcall.MaxResults(1)
 	lcall.ForChannelId(channelId)
-	s.addcost(1)
-	list, err := lcall.Do()
+	lcall.Context(ctx)
+	list, err := do(ctx, s, "subscriptions.list", QUOTA_COST_LIST, lcall.Do)
 	if err != nil {
 		return fmt.Errorf("subs delete error (list.ForChannelId '%s'): %w", channelId, err)
 	}
@@ -102,12 +287,21 @@ func (s *Youtube) SubscriptionDelete(channelId string) error {
 		return fmt.Errorf("subs delete: channelId '%s' not in subscriptions", channelId)
 	}
 	subid := list.Items[0].Id
-	// delete sub
-	call := s.svc.Subscriptions.Delete(subid)
-	s.addcost(50)
-	err = call.Do()
+	if err := s.SubscriptionDeleteById(ctx, subid); err != nil {
+		return fmt.Errorf("%w (channelId '%s')", err, channelId)
+	}
+	return nil
+}
+
+// SubscriptionDeleteById deletes a subscription of the authenticated user's channel by its ID
+// (eg. from SubscriptionsList).
+// The GCloud quota impact is 50 units.
+func (s *Youtube) SubscriptionDeleteById(ctx context.Context, subscriptionId string) error {
+	call := s.svc.Subscriptions.Delete(subscriptionId)
+	call.Context(ctx)
+	err := s.retry(ctx, "subscriptions.delete", QUOTA_COST_DELETE, func() error { return call.Do() })
 	if err != nil {
-		return fmt.Errorf("subs delete error (channelId '%s', subId '%s'): %w", channelId, subid, err)
+		return fmt.Errorf("subs delete error (subId '%s'): %w", subscriptionId, err)
 	}
 	return nil
 }
@@ -117,22 +311,32 @@ func (s *Youtube) SubscriptionDelete(channelId string) error {
 //
 
 // PlaylistsList returns all user playlists.
-// Items will contain the "snippet" (https://developers.google.com/youtube/v3/docs/playlists#snippet)
-// and the "contentDetails" (https://developers.google.com/youtube/v3/docs/playlists#contentDetails) resource properties
-// The GCloud quota impact is 1 unit every 50 items fetched.
-func (s *Youtube) PlaylistsList(out chan<- *Playlist) error {
-	call := s.svc.Playlists.List([]string{"snippet", "contentDetails"})
+// Items will contain the "snippet" (https://developers.google.com/youtube/v3/docs/playlists#snippet),
+// the "contentDetails" (https://developers.google.com/youtube/v3/docs/playlists#contentDetails)
+// and the "status" (https://developers.google.com/youtube/v3/docs/playlists#status) resource properties
+// The GCloud quota impact is 1 unit every 50 items fetched, it stops early with WithListLimit.
+func (s *Youtube) PlaylistsList(ctx context.Context, out chan<- *Playlist) error {
+	call := s.svc.Playlists.List([]string{"snippet", "contentDetails", "status"})
 	call.Mine(true)
-	call.MaxResults(50)
+	call.Context(ctx)
+	count := newListCounter(ctx)
 	t := "-"
 	for t != "" {
-		s.addcost(1)
-		r, err := call.Do()
+		call.MaxResults(count.pageSize())
+		r, err := doPage(ctx, s, "playlists.list", count.params("part=snippet,contentDetails,status&mine=true"), pageToken(t), QUOTA_COST_LIST,
+			func(e string) { call.IfNoneMatch(e) }, call.Do, func(r *youtube.PlaylistListResponse) string { return r.Etag })
 		if err != nil {
 			return fmt.Errorf("playlist list error (page %s): %w", t, err)
 		}
 		for _, v := range r.Items {
-			out <- &Playlist{v}
+			select {
+			case out <- &Playlist{v}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if count.sent() {
+				return nil
+			}
 		}
 		t = r.NextPageToken
 		call.PageToken(t)
@@ -140,47 +344,80 @@ func (s *Youtube) PlaylistsList(out chan<- *Playlist) error {
 	return nil
 }
 
-// PlaylistInsert creates a new private playlist for the authenticated user.
+// PlaylistInfo returns a playlist from ID (a user own playlist or a public one).
+// Returned value will contain the "snippet", "contentDetails" and "status" resource properties.
+// The GCloud quota impact is 1 unit.
+func (s *Youtube) PlaylistInfo(ctx context.Context, id string) (*Playlist, error) {
+	call := s.svc.Playlists.List([]string{"snippet", "contentDetails", "status"})
+	call.Id(id)
+	call.Context(ctx)
+	res, err := do(ctx, s, "playlists.list", QUOTA_COST_LIST, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("playlist list error for id=\"%s\": %w", id, err)
+	} else if len(res.Items) == 0 {
+		return nil, fmt.Errorf("playlist not found for id=\"%s\"", id)
+	}
+	return &Playlist{res.Items[0]}, nil
+}
+
+// PlaylistInsert creates a new playlist for the authenticated user, private if
+// the metadata has no PrivacyStatus.
 // The GCloud quota impact is 50 units.
-func (s *Youtube) PlaylistInsert(title string) (*Playlist, error) {
-	pl := &youtube.Playlist{
-		Snippet: &youtube.PlaylistSnippet{
-			Title: title,
-			// Description:      "",
-			// DefaultLanguage:  "",
-			// Tags: []string{},
-		},
-		Status: &youtube.PlaylistStatus{
-			PrivacyStatus: "private",
-		},
-		// Localizations: map[string]youtube.PlaylistLocalization{},
+func (s *Youtube) PlaylistInsert(ctx context.Context, meta PlaylistMetadata) (*Playlist, error) {
+	if meta.PrivacyStatus == "" {
+		meta.PrivacyStatus = "private"
 	}
-	call := s.svc.Playlists.Insert([]string{"snippet", "status"}, pl)
-	s.addcost(50)
-	pl, err := call.Do()
+	call := s.svc.Playlists.Insert([]string{"snippet", "status"}, meta.playlist())
+	call.Context(ctx)
+	pl, err := do(ctx, s, "playlists.insert", QUOTA_COST_INSERT, call.Do)
 	return &Playlist{pl}, err
 }
 
+// PlaylistUpdate changes the metadata of a playlist of the authenticated user with the
+// update function. The API replaces all the properties, so the current ones are read first.
+// The GCloud quota impact is 51 units.
+func (s *Youtube) PlaylistUpdate(ctx context.Context, playlistId string, update func(*PlaylistMetadata) error) (*Playlist, error) {
+	current, err := s.PlaylistInfo(ctx, playlistId)
+	if err != nil {
+		return nil, err
+	}
+	meta := PlaylistMetadataOf(current)
+	if err := update(&meta); err != nil {
+		return nil, err
+	}
+	pl := meta.playlist()
+	pl.Id = playlistId
+	call := s.svc.Playlists.Update([]string{"snippet", "status"}, pl)
+	call.Context(ctx)
+	pl, err = do(ctx, s, "playlists.update", QUOTA_COST_UPDATE, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("playlist update error (id=\"%s\"): %w", playlistId, err)
+	}
+	return &Playlist{pl}, nil
+}
+
 // PlaylistDelete deletes a playlist from the authenticated user.
 // The GCloud quota impact is 50 units.
-func (s *Youtube) PlaylistDelete(playlistId string) error {
+func (s *Youtube) PlaylistDelete(ctx context.Context, playlistId string) error {
 	call := s.svc.Playlists.Delete(playlistId)
-	s.addcost(50)
-	return call.Do()
+	call.Context(ctx)
+	return s.retry(ctx, "playlists.delete", QUOTA_COST_DELETE, func() error { return call.Do() })
 }
 
 // PlaylistItemsList sends to out the items of a playlist until the filter function returns false.
 // Playlist id can be a user own playlist or a public playlist.
-// Items will contain only the "snippet" resource property (https://developers.google.com/youtube/v3/docs/playlistItems#snippet).
-// The GCloud quota impact is 1 unit every 50 items fetched.
-func (s *Youtube) PlaylistItemsList(playlistId string, filter func(*PlaylistItem) (bool, error), out chan<- *PlaylistItem) error {
-	call := s.svc.PlaylistItems.List([]string{"snippet"})
+// Items will contain the "snippet" and "contentDetails" resource properties (https://developers.google.com/youtube/v3/docs/playlistItems).
+// The GCloud quota impact is 1 unit every 50 items fetched, it stops early with WithListLimit.
+func (s *Youtube) PlaylistItemsList(ctx context.Context, playlistId string, filter func(*PlaylistItem) (bool, error), out chan<- *PlaylistItem) error {
+	call := s.svc.PlaylistItems.List([]string{"snippet", "contentDetails"})
 	call.PlaylistId(playlistId)
-	call.MaxResults(50)
+	call.Context(ctx)
+	count := newListCounter(ctx)
 	t := "-"
 	for t != "" {
-		s.addcost(1)
-		res, err := call.Do()
+		call.MaxResults(count.pageSize())
+		res, err := doPage(ctx, s, "playlistItems.list", count.params("part=snippet,contentDetails&playlistId="+playlistId), pageToken(t), QUOTA_COST_LIST,
+			func(e string) { call.IfNoneMatch(e) }, call.Do, func(r *youtube.PlaylistItemListResponse) string { return r.Etag })
 		if err != nil {
 			return fmt.Errorf("playlist items list error (id=\"%s\" and page=\"%s\"): %w", playlistId, t, err)
 		}
@@ -190,7 +427,14 @@ func (s *Youtube) PlaylistItemsList(playlistId string, filter func(*PlaylistItem
 			if err != nil {
 				return fmt.Errorf("playlist items list filter error (id=\"%s\" and page=\"%s\"): %w", playlistId, t, err)
 			} else if ok {
-				out <- o
+				select {
+				case out <- o:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if count.sent() {
+					return nil
+				}
 			} else {
 				return nil
 			}
@@ -201,9 +445,13 @@ func (s *Youtube) PlaylistItemsList(playlistId string, filter func(*PlaylistItem
 	return nil
 }
 
-// PlaylistItemsInsert adds a video to a playlist.
+// POSITION_APPEND is the position of PlaylistItemsInsert adding the video at the end of the playlist.
+const POSITION_APPEND int64 = -1
+
+// PlaylistItemsInsert adds a video to a playlist at a zero based position
+// (0 is the top of the playlist), or at the end with POSITION_APPEND.
 // The GCloud quota impact is 50 units.
-func (s *Youtube) PlaylistItemsInsert(playlistId, videoId string) (*PlaylistItem, error) {
+func (s *Youtube) PlaylistItemsInsert(ctx context.Context, playlistId, videoId string, position int64) (*PlaylistItem, error) {
 	pli := &youtube.PlaylistItem{
 		// ContentDetails: &youtube.PlaylistItemContentDetails{
 		// 	EndAt:            "",
@@ -212,28 +460,92 @@ func (s *Youtube) PlaylistItemsInsert(playlistId, videoId string) (*PlaylistItem
 		// },
 		Snippet: &youtube.PlaylistItemSnippet{
 			PlaylistId: playlistId,
-			Position:   0,
 			ResourceId: &youtube.ResourceId{
 				Kind:    "youtube#video",
 				VideoId: videoId,
 			},
 		},
 	}
+	if position >= 0 {
+		pli.Snippet.Position = position
+		// a zero position is sent anyway, omitted it would append the video
+		pli.Snippet.ForceSendFields = []string{"Position"}
+	}
 	call := s.svc.PlaylistItems.Insert([]string{"snippet"}, pli)
-	s.addcost(50)
-	pli, err := call.Do()
+	call.Context(ctx)
+	pli, err := do(ctx, s, "playlistItems.insert", QUOTA_COST_INSERT, call.Do)
 	return &PlaylistItem{pli}, err
 }
 
+// PlaylistItemsDelete deletes an item from a playlist.
+// The GCloud quota impact is 50 units.
+func (s *Youtube) PlaylistItemsDelete(ctx context.Context, playlistItemId string) error {
+	call := s.svc.PlaylistItems.Delete(playlistItemId)
+	call.Context(ctx)
+	err := s.retry(ctx, "playlistItems.delete", QUOTA_COST_DELETE, func() error { return call.Do() })
+	if err != nil {
+		return fmt.Errorf("playlist item delete error (id=\"%s\"): %w", playlistItemId, err)
+	}
+	return nil
+}
+
+// PlaylistItemsUpdate moves a playlist item (the video videoId in the playlist playlistId)
+// to a new position. Positions are zero based.
+// The GCloud quota impact is 50 units.
+func (s *Youtube) PlaylistItemsUpdate(ctx context.Context, playlistItemId, playlistId, videoId string, position int64) (*PlaylistItem, error) {
+	pli := &youtube.PlaylistItem{
+		Id: playlistItemId,
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistId,
+			Position:   position,
+			ResourceId: &youtube.ResourceId{
+				Kind:    "youtube#video",
+				VideoId: videoId,
+			},
+			// position 0 must be sent anyway
+			ForceSendFields: []string{"Position"},
+		},
+	}
+	call := s.svc.PlaylistItems.Update([]string{"snippet"}, pli)
+	call.Context(ctx)
+	pli, err := do(ctx, s, "playlistItems.update", QUOTA_COST_UPDATE, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("playlist item update error (id=\"%s\"): %w", playlistItemId, err)
+	}
+	return &PlaylistItem{pli}, nil
+}
+
+// ChannelsList returns the channels of up to 50 IDs, in no particular order.
+// Channels not found are missing from the result.
+// Returned values will contain only the "contentDetails" resource property (https://developers.google.com/youtube/v3/docs/channels#contentDetails).
+// The GCloud quota impact is 1 unit.
+func (s *Youtube) ChannelsList(ctx context.Context, ids []string) ([]*Channel, error) {
+	if len(ids) > MAX_BATCH_IDS {
+		return nil, fmt.Errorf("channel list error: %d ids, max is %d", len(ids), MAX_BATCH_IDS)
+	}
+	call := s.svc.Channels.List([]string{"contentDetails"})
+	call.Id(ids...)
+	call.Context(ctx)
+	res, err := do(ctx, s, "channels.list", QUOTA_COST_LIST, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("channel list error for %d ids: %w", len(ids), err)
+	}
+	out := make([]*Channel, 0, len(res.Items))
+	for _, c := range res.Items {
+		out = append(out, &Channel{c})
+	}
+	return out, nil
+}
+
 // GetChannelInfo returns channel info from ID.
 // Returned value will contain only the "contentDetails" resource property (https://developers.google.com/youtube/v3/docs/channels#contentDetails).
 // The GCloud quota impact is 1 unit
-func (s *Youtube) GetChannelInfo(id string) (*Channel, error) {
+func (s *Youtube) GetChannelInfo(ctx context.Context, id string) (*Channel, error) {
 	call := s.svc.Channels.List([]string{"contentDetails"})
 	// call.ForUsername("username")
 	call.Id(id)
-	s.addcost(1)
-	res, err := call.Do()
+	call.Context(ctx)
+	res, err := do(ctx, s, "channels.list", QUOTA_COST_LIST, call.Do)
 	if err != nil {
 		return nil, fmt.Errorf("channel list error for id=\"%s\": %w", id, err)
 	} else if len(res.Items) == 0 {
@@ -244,3 +556,47 @@ func (s *Youtube) GetChannelInfo(id string) (*Channel, error) {
 	}
 	return &Channel{res.Items[0]}, nil
 }
+
+//
+// VIDEOS
+//
+
+// VideoInfo returns a video from ID.
+// Returned value will contain the "snippet" (https://developers.google.com/youtube/v3/docs/videos#snippet),
+// "contentDetails" and "statistics" resource properties.
+// The GCloud quota impact is 1 unit.
+func (s *Youtube) VideoInfo(ctx context.Context, id string) (*Video, error) {
+	call := s.svc.Videos.List([]string{"snippet", "contentDetails", "statistics"})
+	call.Id(id)
+	call.Context(ctx)
+	res, err := do(ctx, s, "videos.list", QUOTA_COST_LIST, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("video list error for id=\"%s\": %w", id, err)
+	} else if len(res.Items) == 0 {
+		return nil, fmt.Errorf("video not found for id=\"%s\"", id)
+	}
+	return &Video{res.Items[0]}, nil
+}
+
+// VideosList returns the videos of up to 50 IDs, in no particular order.
+// Videos not found (or private) are missing from the result.
+// Returned values will contain the "snippet", "contentDetails", "statistics" and "liveStreamingDetails"
+// (only for live streams and premieres) resource properties.
+// The GCloud quota impact is 1 unit.
+func (s *Youtube) VideosList(ctx context.Context, ids []string) ([]*Video, error) {
+	if len(ids) > MAX_BATCH_IDS {
+		return nil, fmt.Errorf("video list error: %d ids, max is %d", len(ids), MAX_BATCH_IDS)
+	}
+	call := s.svc.Videos.List([]string{"snippet", "contentDetails", "statistics", "liveStreamingDetails"})
+	call.Id(ids...)
+	call.Context(ctx)
+	res, err := do(ctx, s, "videos.list", QUOTA_COST_LIST, call.Do)
+	if err != nil {
+		return nil, fmt.Errorf("video list error for %d ids: %w", len(ids), err)
+	}
+	out := make([]*Video, 0, len(res.Items))
+	for _, v := range res.Items {
+		out = append(out, &Video{v})
+	}
+	return out, nil
+}
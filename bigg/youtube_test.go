@@ -0,0 +1,119 @@
+package bigg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Duration
+	}{
+		{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+		{"PT59S", 59 * time.Second},
+		{"PT15M", 15 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P0D", 0}, // live streams
+	} {
+		got, err := ParseDuration(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: want %v, got: %v (%v)", tc.in, tc.want, got, err)
+		}
+	}
+	for _, in := range []string{"", "P", "1H", "PT1X", "PTH"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("%q: want an error", in)
+		}
+	}
+}
+
+func TestPlaylistItemsInsertPosition(t *testing.T) {
+	var body map[string]map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{"id":"PLI"}`)
+	}))
+	defer ts.Close()
+	s := newTestYoutube(t, ts)
+
+	for _, tc := range []struct {
+		position int64
+		want     interface{}
+	}{
+		{POSITION_APPEND, nil},
+		{0, float64(0)}, // sent even if zero
+		{3, float64(3)},
+	} {
+		if _, err := s.PlaylistItemsInsert(context.Background(), "PL", "V1", tc.position); err != nil {
+			t.Fatal(err)
+		}
+		if got := body["snippet"]["position"]; got != tc.want {
+			t.Errorf("position %d: want %v, got: %v", tc.position, tc.want, got)
+		}
+	}
+}
+
+func TestListLimit(t *testing.T) {
+	// a playlist of 120 items, in pages of maxResults items
+	var sizes []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		sizes = append(sizes, q.Get("maxResults"))
+		from, _ := strconv.Atoi(q.Get("pageToken"))
+		size, _ := strconv.Atoi(q.Get("maxResults"))
+		items := []string{}
+		for i := from; i < from+size && i < 120; i++ {
+			items = append(items, fmt.Sprintf(`{"id":"I%d"}`, i))
+		}
+		next := ""
+		if from+size < 120 {
+			next = strconv.Itoa(from + size)
+		}
+		fmt.Fprintf(w, `{"items":[%s],"nextPageToken":"%s"}`, strings.Join(items, ","), next)
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		limit int
+		want  []string
+		items int
+	}{
+		{0, []string{"50", "50", "50"}, 120},
+		{10, []string{"10"}, 10},
+		{50, []string{"50"}, 50},
+		{60, []string{"50", "10"}, 60},
+		{200, []string{"50", "50", "50"}, 120},
+	} {
+		sizes = nil
+		s := newTestYoutube(t, ts)
+		out := make(chan *PlaylistItem, 200)
+		err := s.PlaylistItemsList(WithListLimit(context.Background(), tc.limit), "PL",
+			func(*PlaylistItem) (bool, error) { return true, nil }, out)
+		close(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tc.want, sizes); diff != "" {
+			t.Errorf("limit %d: page sizes mismatch (-want +got):\n%s", tc.limit, diff)
+		}
+		if got := len(out); got != tc.items {
+			t.Errorf("limit %d: want %d items, got: %d", tc.limit, tc.items, got)
+		}
+		if got := s.GetCost(); got != uint32(len(tc.want)) {
+			t.Errorf("limit %d: want cost %d, got: %d", tc.limit, len(tc.want), got)
+		}
+	}
+}
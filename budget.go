@@ -0,0 +1,151 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BudgetPolicy selects what a flow does when its estimated quota cost
+// exceeds the remaining budget.
+type BudgetPolicy int
+
+const (
+	// BudgetRefuse fails the flow before any API call is made.
+	BudgetRefuse BudgetPolicy = iota
+	// BudgetTruncate processes only the inputs that fit in the budget.
+	BudgetTruncate
+	// BudgetWarn only logs a warning, the flow runs anyway.
+	BudgetWarn
+)
+
+var ErrQuotaBudgetExceeded = errors.New("quota budget exceeded")
+
+// QuotaCounter is implemented by services that keep track of the quota they spent
+// (like bigg.Youtube). The Toolkit uses it to stop flows once the budget is reached.
+type QuotaCounter interface {
+	GetCost() uint32
+}
+
+// SetQuotaBudget limits the quota units the Toolkit can spend, as reported by
+// the service GetCost (see QuotaCounter).
+// Flows estimate their cost up front from the input size and apply the policy,
+// then stop cleanly as soon as the next API call would exceed the budget.
+func (tk *Toolkit) SetQuotaBudget(units uint32, policy BudgetPolicy) {
+	tk.budget = units
+	tk.budgetPolicy = policy
+	tk.hasBudget = true
+}
+
+// spentQuota returns the units spent so far by the service (zero if unknown).
+func (tk *Toolkit) spentQuota() uint32 {
+	if qc, ok := tk.service.(QuotaCounter); ok {
+		return qc.GetCost()
+	}
+	return 0
+}
+
+// remainingQuota returns the budget units still available.
+func (tk *Toolkit) remainingQuota() uint32 {
+	spent := tk.spentQuota()
+	if spent >= tk.budget {
+		return 0
+	}
+	return tk.budget - spent
+}
+
+// quotaError returns an ErrQuotaBudgetExceeded error if an API call of the
+// given cost would exceed the budget.
+func (tk *Toolkit) quotaError(cost uint32) error {
+	if !tk.hasBudget {
+		return nil
+	}
+	if remaining := tk.remainingQuota(); cost > remaining {
+		return fmt.Errorf("%w: %d units needed, %d remaining", ErrQuotaBudgetExceeded, cost, remaining)
+	}
+	return nil
+}
+
+// checkQuota is like quotaError but applies the BudgetWarn policy.
+func (tk *Toolkit) checkQuota(cost uint32) error {
+	err := tk.quotaError(cost)
+	if err != nil && tk.budgetPolicy == BudgetWarn {
+		tk.log("Warning:", err)
+		return nil
+	}
+	return err
+}
+
+// budgetGate is used by stages to stop calling the API once the quota budget
+// is reached. The budget error is reported only once per stage.
+type budgetGate struct {
+	tk     *Toolkit
+	errors chan<- error
+	once   sync.Once
+}
+
+func (tk *Toolkit) newBudgetGate(errors chan<- error) *budgetGate {
+	return &budgetGate{tk: tk, errors: errors}
+}
+
+// allows returns false (reporting the error) if a call of the given cost would exceed the budget.
+// With the BudgetWarn policy, it logs a warning (only once) and returns true.
+func (g *budgetGate) allows(cost uint32) bool {
+	err := g.tk.quotaError(cost)
+	if err == nil {
+		return true
+	}
+	warn := g.tk.budgetPolicy == BudgetWarn
+	g.once.Do(func() {
+		if warn {
+			g.tk.log("Warning:", err)
+		} else {
+			g.tk.log("quota budget reached, stopping")
+			g.errors <- err
+		}
+	})
+	return warn
+}
+
+// budgetEstimate buffers all the input to estimate the cost of a flow
+// (costPerItem units for each input) and applies the budget policy:
+// it emits nothing on BudgetRefuse, only the first inputs that fit
+// the budget on BudgetTruncate or everything (with a warning) on BudgetWarn.
+// Without a budget, input is passed through.
+func (tk *Toolkit) budgetEstimate(ctx context.Context, errors chan<- error, input <-chan string, costPerItem uint32) <-chan string {
+	if !tk.hasBudget {
+		return input
+	}
+	output := make(chan string)
+	go func() {
+		defer close(output)
+		ids := []string{}
+		for id := range input {
+			ids = append(ids, id)
+		}
+		estimate := uint32(len(ids)) * costPerItem
+		remaining := tk.remainingQuota()
+		tk.log("Estimated quota cost:", estimate, "units (budget remaining:", remaining, "units)")
+		if estimate > remaining {
+			err := fmt.Errorf("%w: estimated %d units for %d items, %d remaining", ErrQuotaBudgetExceeded, estimate, len(ids), remaining)
+			switch tk.budgetPolicy {
+			case BudgetRefuse:
+				errors <- err
+				return
+			case BudgetTruncate:
+				fit := int(remaining / costPerItem)
+				tk.log("Quota budget allows only", fit, "of", len(ids), "items")
+				ids = ids[:fit]
+			case BudgetWarn:
+				tk.log("Warning:", err)
+			}
+		}
+		for _, id := range ids {
+			if !send(ctx, output, id) {
+				return
+			}
+		}
+	}()
+	return output
+}
@@ -0,0 +1,412 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+	"google.golang.org/api/youtube/v3"
+)
+
+// Kinds of the resources stored in a Cache.
+const (
+	CacheChannels  = "channels"
+	CachePlaylists = "playlists"
+	CacheVideos    = "videos"
+)
+
+// Cache is an on-disk store of API resources (as JSON) by kind and ID, persisted as a JSON file.
+// It's safe for concurrent use.
+type Cache struct {
+	file string
+
+	mu    sync.Mutex
+	dirty bool
+	// kind -> id -> entry
+	Entries map[string]map[string]cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// CacheStats are the number of entries of a kind in a Cache.
+type CacheStats struct {
+	Kind    string
+	Entries int
+	Oldest  time.Time
+}
+
+// DefaultCacheFile returns the cache file in the user cache directory.
+func DefaultCacheFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "youtubetoolkit", "cache.json"), nil
+}
+
+// LoadCache reads a cache file. A missing file results in an empty cache.
+func LoadCache(file string) (*Cache, error) {
+	c := &Cache{file: file, Entries: map[string]map[string]cacheEntry{}}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cache read error: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("cache file %s corrupted (remove it or run cache clear): %w", file, err)
+	}
+	if c.Entries == nil {
+		c.Entries = map[string]map[string]cacheEntry{}
+	}
+	return c, nil
+}
+
+// Save writes the cache file, if anything changed since it was loaded.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("cache write error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0700); err != nil {
+		return fmt.Errorf("cache write error: %w", err)
+	}
+	if err := os.WriteFile(c.file, data, 0600); err != nil {
+		return fmt.Errorf("cache write error: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// Clear removes all the entries and the cache file.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries = map[string]map[string]cacheEntry{}
+	c.dirty = false
+	return ClearCache(c.file)
+}
+
+// ClearCache removes a cache file (even if corrupted).
+func ClearCache(file string) error {
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cache clear error: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the number of entries by kind.
+func (c *Cache) Stats() []CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := []CacheStats{}
+	for kind, entries := range c.Entries {
+		s := CacheStats{Kind: kind, Entries: len(entries)}
+		for _, e := range entries {
+			if s.Oldest.IsZero() || e.Time.Before(s.Oldest) {
+				s.Oldest = e.Time
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// get returns the data of an entry younger than ttl. Expired entries are removed.
+func (c *Cache) get(kind, id string, ttl time.Duration) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.Entries[kind][id]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(e.Time) > ttl {
+		delete(c.Entries[kind], id)
+		c.dirty = true
+		return nil, false
+	}
+	return e.Data, true
+}
+
+func (c *Cache) remove(kind, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.Entries[kind][id]; ok {
+		delete(c.Entries[kind], id)
+		c.dirty = true
+	}
+}
+
+func (c *Cache) put(kind, id string, data json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, ok := c.Entries[kind]
+	if !ok {
+		entries = map[string]cacheEntry{}
+		c.Entries[kind] = entries
+	}
+	entries[id] = cacheEntry{time.Now(), data}
+	c.dirty = true
+}
+
+// CacheTTL are the max ages of the cached resources by kind. Zero disables the cache of a kind.
+type CacheTTL struct {
+	Channels  time.Duration
+	Playlists time.Duration
+	Videos    time.Duration
+}
+
+// DefaultCacheTTL caches channels (their uploads playlist ID never changes) for a month,
+// playlists (their titles change) for a day and videos for an hour: the cached statistics
+// (ViewCount, LikeCount) and live status are as old as the entry.
+var DefaultCacheTTL = CacheTTL{
+	Channels:  30 * 24 * time.Hour,
+	Playlists: 24 * time.Hour,
+	Videos:    time.Hour,
+}
+
+// CachedService is a YoutubeService that serves the channels, playlists and videos
+// metadata from a Cache, calling the wrapped service only for the missing or expired ones.
+type CachedService struct {
+	YoutubeService
+
+	cache *Cache
+	ttl   CacheTTL
+
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+func NewCachedService(svc YoutubeService, cache *Cache, ttl CacheTTL) *CachedService {
+	return &CachedService{YoutubeService: svc, cache: cache, ttl: ttl}
+}
+
+// GetCost returns the quota spent by the wrapped service.
+func (c *CachedService) GetCost() uint32 {
+	if qc, ok := c.YoutubeService.(QuotaCounter); ok {
+		return qc.GetCost()
+	}
+	return 0
+}
+
+// Counts returns the cache hits and misses so far.
+func (c *CachedService) Counts() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *CachedService) count(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// cached returns the resource of kind and id from the cache or, if missing or expired,
+// from fetch (storing it). An entry of a resource not found (see cachedBatch) is fetched
+// again, to return the error of the service.
+func cached[T any](c *CachedService, kind, id string, ttl time.Duration, fetch func() (*T, error)) (*T, error) {
+	if ttl > 0 {
+		if data, ok := c.cache.get(kind, id, ttl); ok && !isNotFound(data) {
+			v := new(T)
+			if err := json.Unmarshal(data, v); err == nil {
+				c.count(true)
+				return v, nil
+			}
+		}
+	}
+	c.count(false)
+	v, err := fetch()
+	if err != nil || ttl <= 0 {
+		return v, err
+	}
+	if data, err := json.Marshal(v); err == nil {
+		c.cache.put(kind, id, data)
+	}
+	return v, nil
+}
+
+// GetChannelInfo implements YoutubeService
+func (c *CachedService) GetChannelInfo(ctx context.Context, id string) (*bigg.Channel, error) {
+	ch, err := cached(c, CacheChannels, id, c.ttl.Channels, func() (*youtube.Channel, error) {
+		ch, err := c.YoutubeService.GetChannelInfo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return ch.Channel, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bigg.Channel{Channel: ch}, nil
+}
+
+// PlaylistInfo implements YoutubeService
+func (c *CachedService) PlaylistInfo(ctx context.Context, id string) (*bigg.Playlist, error) {
+	pl, err := cached(c, CachePlaylists, id, c.ttl.Playlists, func() (*youtube.Playlist, error) {
+		pl, err := c.YoutubeService.PlaylistInfo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return pl.Playlist, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bigg.Playlist{Playlist: pl}, nil
+}
+
+// PlaylistUpdate implements YoutubeService
+// The cached playlist is dropped.
+func (c *CachedService) PlaylistUpdate(ctx context.Context, playlistId string, update func(*bigg.PlaylistMetadata) error) (*bigg.Playlist, error) {
+	defer c.cache.remove(CachePlaylists, playlistId)
+	return c.YoutubeService.PlaylistUpdate(ctx, playlistId, update)
+}
+
+// PlaylistDelete implements YoutubeService
+// The cached playlist is dropped.
+func (c *CachedService) PlaylistDelete(ctx context.Context, playlistId string) error {
+	defer c.cache.remove(CachePlaylists, playlistId)
+	return c.YoutubeService.PlaylistDelete(ctx, playlistId)
+}
+
+// VideoInfo implements YoutubeService
+func (c *CachedService) VideoInfo(ctx context.Context, id string) (*bigg.Video, error) {
+	v, err := cached(c, CacheVideos, id, c.ttl.Videos, func() (*youtube.Video, error) {
+		v, err := c.YoutubeService.VideoInfo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return v.Video, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bigg.Video{Video: v}, nil
+}
+
+// notFound is the data of the entry of a resource not found (eg. a deleted or private video).
+var notFound = json.RawMessage("null")
+
+func isNotFound(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == string(notFound)
+}
+
+// cachedBatch is like cached for a batch lookup: the resources of the missing or expired
+// IDs are fetched with a single call (if any). The IDs not found are cached too (with the
+// same ttl), so they aren't looked up again on every run.
+func cachedBatch[T any](c *CachedService, kind string, ids []string, ttl time.Duration, fetch func([]string) ([]*T, error), idOf func(*T) string) ([]*T, error) {
+	out := []*T{}
+	missing := []string{}
+	for _, id := range ids {
+		if ttl > 0 {
+			if data, ok := c.cache.get(kind, id, ttl); ok {
+				if isNotFound(data) {
+					c.count(true)
+					continue
+				}
+				v := new(T)
+				if err := json.Unmarshal(data, v); err == nil {
+					c.count(true)
+					out = append(out, v)
+					continue
+				}
+			}
+		}
+		c.count(false)
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	res, err := fetch(missing)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, v := range res {
+		found[idOf(v)] = true
+		if ttl > 0 {
+			if data, err := json.Marshal(v); err == nil {
+				c.cache.put(kind, idOf(v), data)
+			}
+		}
+		out = append(out, v)
+	}
+	if ttl > 0 {
+		for _, id := range missing {
+			if !found[id] {
+				c.cache.put(kind, id, notFound)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ChannelsList implements YoutubeService
+func (c *CachedService) ChannelsList(ctx context.Context, ids []string) ([]*bigg.Channel, error) {
+	chs, err := cachedBatch(c, CacheChannels, ids, c.ttl.Channels, func(ids []string) ([]*youtube.Channel, error) {
+		res, err := c.YoutubeService.ChannelsList(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*youtube.Channel, len(res))
+		for i, ch := range res {
+			out[i] = ch.Channel
+		}
+		return out, nil
+	}, func(ch *youtube.Channel) string { return ch.Id })
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*bigg.Channel, len(chs))
+	for i, ch := range chs {
+		out[i] = &bigg.Channel{Channel: ch}
+	}
+	return out, nil
+}
+
+// VideosList implements YoutubeService
+func (c *CachedService) VideosList(ctx context.Context, ids []string) ([]*bigg.Video, error) {
+	vs, err := cachedBatch(c, CacheVideos, ids, c.ttl.Videos, func(ids []string) ([]*youtube.Video, error) {
+		res, err := c.YoutubeService.VideosList(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*youtube.Video, len(res))
+		for i, v := range res {
+			out[i] = v.Video
+		}
+		return out, nil
+	}, func(v *youtube.Video) string { return v.Id })
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*bigg.Video, len(vs))
+	for i, v := range vs {
+		out[i] = &bigg.Video{Video: v}
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/raffaelecassia/youtubetoolkit"
+	"github.com/spf13/cobra"
+)
+
+func Cache(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Cache of channels, playlists and videos metadata",
+		Long: `Channels, playlists and videos metadata are cached in the --cache-file file, so that
+the next executions don't pay for them again (eg. lastuploads looks up each channel only once).
+Entries expire after --cache-ttl-channels, --cache-ttl-playlists and --cache-ttl-videos
+(the statistics of the cached videos, eg. ViewCount of --details, are as old as the entry).
+Use --no-cache to bypass the cache.`,
+		Args: cobra.NoArgs,
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func CacheStats(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "stats",
+		Short:       "Prints the number of cached entries",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{NOLOGIN: "true"},
+		Run: func(c *cobra.Command, _ []string) {
+			file, _ := c.Flags().GetString("cache-file")
+			cache, err := youtubetoolkit.LoadCache(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(w, "Cache file\t%s\n", file)
+			for _, s := range cache.Stats() {
+				fmt.Fprintf(w, "%s\t%d\t(oldest %s)\n", s.Kind, s.Entries, s.Oldest.Format("2006-01-02 15:04"))
+			}
+			if err := w.Flush(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func CacheClear(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "clear",
+		Short:       "Removes all the cached entries",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{NOLOGIN: "true"},
+		Run: func(c *cobra.Command, _ []string) {
+			file, _ := c.Flags().GetString("cache-file")
+			if err := youtubetoolkit.ClearCache(file); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
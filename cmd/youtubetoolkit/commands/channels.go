@@ -11,24 +11,44 @@ import (
 
 func LastUploads(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
 	var days uint16
+	var details bool
+	var exclude string
+	var minDuration, maxDuration time.Duration
 	cmd := &cobra.Command{
 		Use:   "lastuploads [channel id]",
 		Short: "Returns channels' last video uploads",
 		Long: `Returns channels' last video uploads sorted by the published date (oldest first).
 Multiple channel IDs are received from stdin (one per line, or a csv with ids in the first column).
+The uploads of standard channel IDs (UC...) are read directly from their uploads playlist (UU...),
+other channels are looked up first, 50 at a time (1 more unit every 50 channels).
 Available fields for CSV/Table output: VideoId*, VideoTitle*, VideoUrl, PublishedAt*, ChannelId*, ChannelTitle*, ChannelUrl.
+With --details (1 more unit every 50 videos): Duration, DurationSeconds, ViewCount, LikeCount,
+LiveBroadcastContent ("none", "live" or "upcoming") and Definition ("hd" or "sd").
+--exclude skips some kinds of uploads: shorts and live (listing only the variants of the
+uploads playlist with the other kinds, 1 unit each), upcoming and premiere (from the video details).
+--min-duration and --max-duration also use the video details.
 (* default fields when --fields is not specified)`,
-		Args: cobra.MaximumNArgs(1),
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{ITEMS: "videos"},
 		Run: func(c *cobra.Command, args []string) {
 			since := time.Now().Add(-time.Hour * time.Duration(24*int64(days)))
-			output := outputFromFlags(c, DEFAULT_FIELDS_UPLOADS_PLAYLIST)
+			opts := []youtubetoolkit.FlowOption{outputFromFlags(c, DEFAULT_FIELDS_UPLOADS_PLAYLIST)}
+			if details {
+				opts = append(opts, youtubetoolkit.VideoDetails())
+			}
+			kinds, err := youtubetoolkit.ParseVideoKinds(exclude)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			opts = append(opts, youtubetoolkit.ExcludeVideos(kinds...), youtubetoolkit.VideoDuration(minDuration, maxDuration))
 			if len(args) == 1 {
-				err := tk.LastUploads(since, youtubetoolkit.SingleStringSource(args[0]), output)
+				err := tk.LastUploadsContext(c.Context(), since, append(opts, youtubetoolkit.SingleStringSource(args[0]))...)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, "Error:", err)
 				}
 			} else if checkStdinInput() {
-				err := tk.LastUploads(since, youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin), output)
+				err := tk.LastUploadsContext(c.Context(), since, append(opts, youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin))...)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, "Error:", err)
 				}
@@ -42,6 +62,10 @@ Available fields for CSV/Table output: VideoId*, VideoTitle*, VideoUrl, Publishe
 		},
 	}
 	cmd.Flags().Uint16VarP(&days, "days", "", 7, "days since")
+	cmd.Flags().BoolVar(&details, "details", false, "also looks up the video details (duration, statistics, live status and definition)")
+	cmd.Flags().StringVar(&exclude, "exclude", "", "comma separated kinds of uploads to skip: shorts, live, upcoming, premiere")
+	cmd.Flags().DurationVar(&minDuration, "min-duration", 0, "skips the videos shorter than this (eg. 2m)")
+	cmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "skips the videos longer than this (eg. 1h)")
 	parent.AddCommand(cmd)
 	return cmd
 }
@@ -3,8 +3,10 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/raffaelecassia/youtubetoolkit"
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
 	"github.com/spf13/cobra"
 )
 
@@ -13,10 +15,12 @@ func Playlists(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command
 		Use:   "playlists",
 		Short: "Manage user playlists",
 		Long: `Returns all user playlists.
-Available fields for CSV/Table output: PlaylistId, PlaylistTitle, VideoCount.`,
-		Args: cobra.NoArgs,
+Available fields for CSV/Table output: PlaylistId, PlaylistTitle, VideoCount, Description,
+PrivacyStatus ("public", "unlisted" or "private") and PublishedAt.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "playlists"},
 		Run: func(c *cobra.Command, _ []string) {
-			err := tk.Playlists(outputFromFlags(c, DEFAULT_FIELDS_PLAYLISTS))
+			err := tk.PlaylistsContext(c.Context(), outputFromFlags(c, DEFAULT_FIELDS_PLAYLISTS))
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
@@ -28,21 +32,30 @@ Available fields for CSV/Table output: PlaylistId, PlaylistTitle, VideoCount.`,
 
 func Playlist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
 	var id string
+	var details bool
 	cmd := &cobra.Command{
 		Use:   "playlist",
 		Short: "Manage a playlist",
 		Long: `Returns all videos of a playlist.
-Fields for CSV/Table output: VideoId*, VideoTitle*, VideoUrl*, ChannelId*, ChannelTitle*, ChannelUrl*, PlaylistItemId.
+Fields for CSV/Table output: VideoId*, VideoTitle*, VideoUrl*, ChannelId*, ChannelTitle*, ChannelUrl*, PlaylistItemId, PublishedAt, VideoPublishedAt, Position.
+With --details (1 more unit every 50 videos): Duration, DurationSeconds, ViewCount, LikeCount,
+LiveBroadcastContent ("none", "live" or "upcoming") and Definition ("hd" or "sd").
 (* default fields when --fields is not specified)`,
-		Args: cobra.NoArgs,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "videos"},
 		Run: func(c *cobra.Command, _ []string) {
-			err := tk.Playlist(id, outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST))
+			opts := []youtubetoolkit.FlowOption{outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST)}
+			if details {
+				opts = append(opts, youtubetoolkit.VideoDetails())
+			}
+			err := tk.PlaylistContext(c.Context(), id, opts...)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
 		},
 	}
 	cmd.PersistentFlags().StringVarP(&id, "id", "", "", "playlist id, mandatory")
+	cmd.Flags().BoolVar(&details, "details", false, "also looks up the video details (duration, statistics, live status and definition)")
 	err := cmd.MarkPersistentFlagRequired("id")
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -52,24 +65,33 @@ Fields for CSV/Table output: VideoId*, VideoTitle*, VideoUrl*, ChannelId*, Chann
 }
 
 func NewPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var position string
+	var meta bigg.PlaylistMetadata
 	cmd := &cobra.Command{
 		Use:   "new [playlist name]",
 		Short: "Creates a new playlist",
-		Long: `Creates a new playlist.
+		Long: `Creates a new playlist (private, unless --privacy is used).
 To also add videos to the newly created playlist, send to stdin a list of 
-video ids (or a CSV with ids in the first column).
+video ids (or a CSV with ids in the first column), added in the same order
+(eg. oldest first from lastuploads).
 Prints to stdout the playlist id.`,
 		Args: cobra.ExactArgs(1),
-		Run: func(_ *cobra.Command, args []string) {
-			id, err := tk.NewPlaylist(args[0])
+		Run: func(c *cobra.Command, args []string) {
+			insertAt, err := positionOption(position)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			meta.Title = args[0]
+			id, err := tk.NewPlaylistWithMetadataContext(c.Context(), meta)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			} else {
 				fmt.Fprintln(os.Stdout, id)
 				if checkStdinInput() {
-					err := tk.AddVideoToPlaylist(id,
+					err := tk.AddVideoToPlaylistContext(c.Context(), id,
 						youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin),
-						youtubetoolkit.NullSink())
+						youtubetoolkit.NullSink(), insertAt)
 					if err != nil {
 						fmt.Fprintln(os.Stderr, "Error:", err)
 					}
@@ -77,53 +99,177 @@ Prints to stdout the playlist id.`,
 			}
 		},
 	}
+	addPositionFlag(cmd, &position)
+	addPlaylistMetadataFlags(cmd, &meta)
+	addDryRunFlag(cmd)
 	parent.AddCommand(cmd)
 	return cmd
 }
 
+func ClonePlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var print bool
+	var meta bigg.PlaylistMetadata
+	cmd := &cobra.Command{
+		Use:   "clone [source playlist id]",
+		Short: "Copies a playlist into a new one",
+		Long: `Copies a playlist (eg. a public one of another channel) into a new playlist of the user,
+with the same videos in the same order. Deleted and private videos are skipped.
+The title defaults to the one of the source playlist, the playlist is private unless --privacy is used.
+The source is read first (1 unit every 50 videos) and the quota cost is printed
+(50 units for the playlist and each video): a big playlist can exceed the daily quota,
+use --journal and, the day after, --resume with the same file to continue in the same playlist.
+Prints to stdout the new playlist id (and with --print-data the added videos, with the default
+fields from the playlist command).`,
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{ITEMS: "videos"},
+		Run: func(c *cobra.Command, args []string) {
+			output := youtubetoolkit.NullSink()
+			if print {
+				output = outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST)
+			}
+			journal, err := journalFromFlags(c)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			defer journal.Close()
+			id, err := tk.ClonePlaylistContext(c.Context(), args[0], meta, output, youtubetoolkit.WithJournal(journal))
+			if id != "" && print {
+				fmt.Fprintln(os.Stderr, "Playlist id:", id)
+			} else if id != "" {
+				fmt.Fprintln(os.Stdout, id)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&print, "print-data", "p", false, "print to stdout the playlist/video infos of the added videos (instead of the playlist id)")
+	cmd.Flags().StringVar(&meta.Title, "title", "", "new playlist title (default the source title)")
+	addPlaylistMetadataFlags(cmd, &meta)
+	addDryRunFlag(cmd)
+	addJournalFlags(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func UpdatePlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var title string
+	var meta bigg.PlaylistMetadata
+	cmd := &cobra.Command{
+		Use:   "update [playlist id]",
+		Short: "Changes the metadata of a playlist",
+		Long: `Changes the title, privacy, description, tags or language of a playlist, keeping
+the other ones (use eg. --description "" to clear one).
+The playlist is read first: 51 units.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			flags := c.Flags()
+			err := tk.UpdatePlaylistContext(c.Context(), args[0], func(m *bigg.PlaylistMetadata) {
+				if flags.Changed("title") {
+					m.Title = title
+				}
+				if flags.Changed("privacy") {
+					m.PrivacyStatus = meta.PrivacyStatus
+				}
+				if flags.Changed("description") {
+					m.Description = meta.Description
+				}
+				if flags.Changed("tags") {
+					m.Tags = meta.Tags
+				}
+				if flags.Changed("language") {
+					m.DefaultLanguage = meta.DefaultLanguage
+				}
+			})
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&title, "title", "", "playlist title")
+	addPlaylistMetadataFlags(cmd, &meta)
+	addDryRunFlag(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func addPlaylistMetadataFlags(cmd *cobra.Command, meta *bigg.PlaylistMetadata) {
+	cmd.Flags().StringVar(&meta.PrivacyStatus, "privacy", "", "playlist privacy: public, unlisted or private")
+	cmd.Flags().StringVar(&meta.Description, "description", "", "playlist description")
+	cmd.Flags().StringSliceVar(&meta.Tags, "tags", nil, "playlist tags (comma separated)")
+	cmd.Flags().StringVar(&meta.DefaultLanguage, "language", "", `playlist default language (eg. "en")`)
+}
+
 func DelPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "del [playlist id]",
 		Short: "Deletes a playlist",
 		Args:  cobra.ExactArgs(1),
-		Run: func(_ *cobra.Command, args []string) {
-			err := tk.DeletePlaylist(args[0])
+		Run: func(c *cobra.Command, args []string) {
+			err := tk.DeletePlaylistContext(c.Context(), args[0])
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
 		},
 	}
+	addDryRunFlag(cmd)
 	parent.AddCommand(cmd)
 	return cmd
 }
 
 func AddToPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
 	var print bool
+	var skipExisting bool
+	var position string
 	cmd := &cobra.Command{
 		Use:   "add [video id]",
 		Short: "Adds a video to a playlist",
 		Long: `Adds a video to a playlist.
 To add multiple videos, send to stdin a list of video ids (or a CSV with ids in the first column).
 The flag --id is mandatory.
-If --print-data flag is used, the default fields from the playlist command will apply.`,
-		Args: cobra.MaximumNArgs(1),
+Videos are appended to the playlist in input order, --position prepend or --position <n>
+inserts them (still in input order) at the top or from the zero based position n.
+If --print-data flag is used, the default fields from the playlist command will apply
+(plus the Status field, "added" or "skipped", when --skip-existing is used).
+With --skip-existing, the playlist videos are loaded first (1 unit every 50 videos) and
+videos already in the playlist (or duplicated in input) are skipped.`,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{ITEMS: "videos"},
 		Run: func(c *cobra.Command, args []string) {
 			var output youtubetoolkit.FlowOption
-			if print {
+			if print && skipExisting {
+				output = outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST_ADD_SKIP)
+			} else if print {
 				output = outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST)
 			} else {
 				output = youtubetoolkit.NullSink()
 			}
+			insertAt, err := positionOption(position)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			journal, err := journalFromFlags(c)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			defer journal.Close()
+			opts := []youtubetoolkit.FlowOption{output, youtubetoolkit.WithJournal(journal), insertAt}
+			if skipExisting {
+				opts = append(opts, youtubetoolkit.SkipExisting())
+			}
 
 			playlistId := c.Flag("id").Value.String()
 			if len(args) == 1 {
-				err := tk.AddVideoToPlaylist(playlistId, youtubetoolkit.SingleStringSource(args[0]), output)
+				err := tk.AddVideoToPlaylistContext(c.Context(), playlistId, append(opts, youtubetoolkit.SingleStringSource(args[0]))...)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, "Error:", err)
 				}
 			} else {
 				if checkStdinInput() {
-					err := tk.AddVideoToPlaylist(playlistId, youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin), output)
+					err := tk.AddVideoToPlaylistContext(c.Context(), playlistId, append(opts, youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin))...)
 					if err != nil {
 						fmt.Fprintln(os.Stderr, "Error:", err)
 					}
@@ -139,6 +285,158 @@ If --print-data flag is used, the default fields from the playlist command will
 		},
 	}
 	cmd.Flags().BoolVarP(&print, "print-data", "p", false, "print to stdout the playlist/video infos of the added video(s)")
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "skips videos already in the playlist (and duplicates in input)")
+	addPositionFlag(cmd, &position)
+	addDryRunFlag(cmd)
+	addJournalFlags(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func RemoveFromPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var print bool
+	cmd := &cobra.Command{
+		Use:   "del [video id or playlist item id]",
+		Short: "Removes a video from a playlist",
+		Long: `Removes a video from a playlist.
+To remove multiple videos, send to stdin a list of video ids or playlist item ids
+(or a CSV with ids in the first column, eg. the output of the playlist command).
+The flag --id is mandatory.
+The playlist videos are loaded first (1 unit every 50 videos) to find the items to delete
+(50 units each); all the items of a video id are deleted.
+If --print-data flag is used, the default fields from the playlist command will apply.`,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{ITEMS: "videos"},
+		Run: func(c *cobra.Command, args []string) {
+			output := youtubetoolkit.NullSink()
+			if print {
+				output = outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST)
+			}
+			journal, err := journalFromFlags(c)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			defer journal.Close()
+			opts := []youtubetoolkit.FlowOption{output, youtubetoolkit.WithJournal(journal)}
+
+			playlistId := c.Flag("id").Value.String()
+			if len(args) == 1 {
+				opts = append(opts, youtubetoolkit.SingleStringSource(args[0]))
+			} else if checkStdinInput() {
+				opts = append(opts, youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin))
+			} else {
+				err := c.Help()
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				os.Exit(0)
+			}
+			err = tk.RemoveVideoFromPlaylistContext(c.Context(), playlistId, opts...)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&print, "print-data", "p", false, "print to stdout the playlist/video infos of the removed video(s)")
+	addDryRunFlag(cmd)
+	addJournalFlags(cmd)
 	parent.AddCommand(cmd)
 	return cmd
 }
+
+func SyncPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Makes a playlist match a list of videos",
+		Long: `Makes a playlist contain exactly the videos sent to stdin (a list of video ids
+or a CSV with ids in the first column), in the same order: missing videos are added at
+their position, extra ones are deleted and the others are moved where needed.
+The flag --id is mandatory.
+The quota cost of the plan (50 units for each change) is printed before applying it.
+Prints to stdout the changes, with the default fields VideoId, VideoTitle, Status
+("added", "deleted" or "moved") and Position. Use --dry-run to only see the plan.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "videos"},
+		Run: func(c *cobra.Command, _ []string) {
+			if !checkStdinInput() {
+				err := c.Help()
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				os.Exit(0)
+			}
+			playlistId := c.Flag("id").Value.String()
+			err := tk.SyncPlaylistContext(c.Context(), playlistId,
+				youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin),
+				outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST_SYNC))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	addDryRunFlag(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func SortPlaylist(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var by string
+	cmd := &cobra.Command{
+		Use:   "sort",
+		Short: "Sorts a playlist in place",
+		Long: `Sorts the videos of a playlist, keeping its ID: only the videos out of order are moved
+(50 units each, with the fewest moves).
+The flag --id is mandatory.
+--by is a comma separated list of fields, with ":desc" for descending order, eg.:
+  VideoPublishedAt     when the video was published
+  PublishedAt          when the video was added to the playlist
+  VideoTitle, ChannelTitle
+  Duration             with the video details (1 more unit every 50 videos), like ViewCount
+The quota cost of the plan is printed before applying it.
+Prints to stdout the moved videos, with the default fields VideoId, VideoTitle, Status
+("moved") and Position. Use --dry-run to only see the plan.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "videos"},
+		Run: func(c *cobra.Command, _ []string) {
+			keys, err := youtubetoolkit.ParseSortKeys(by, youtubetoolkit.PlaylistVideoItem)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			playlistId := c.Flag("id").Value.String()
+			err = tk.SortPlaylistContext(c.Context(), playlistId, keys,
+				outputFromFlags(c, DEFAULT_FIELDS_PLAYLIST_SYNC))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&by, "by", "", `fields to sort by, mandatory (eg. "VideoPublishedAt" or "Duration:desc,VideoTitle")`)
+	err := cmd.MarkFlagRequired("by")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	addDryRunFlag(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func addPositionFlag(cmd *cobra.Command, position *string) {
+	cmd.Flags().StringVar(position, "position", "append", `where the videos are inserted: "append", "prepend" or a zero based position`)
+}
+
+// positionOption returns the FlowOption of a --position flag.
+func positionOption(position string) (youtubetoolkit.FlowOption, error) {
+	switch position {
+	case "append":
+		return youtubetoolkit.InsertAt(bigg.POSITION_APPEND), nil
+	case "prepend":
+		return youtubetoolkit.InsertAt(0), nil
+	}
+	n, err := strconv.ParseInt(position, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid --position %q (append, prepend or a zero based position)", position)
+	}
+	return youtubetoolkit.InsertAt(n), nil
+}
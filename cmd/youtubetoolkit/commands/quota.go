@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raffaelecassia/youtubetoolkit"
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+	"github.com/spf13/cobra"
+)
+
+func Quota(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Daily quota usage",
+		Args:  cobra.NoArgs,
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func QuotaStatus(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Prints today's quota usage",
+		Long: `Prints the quota used today (by api method) and the quota left, as recorded by previous
+executions in the --quota-ledger file. The quota day resets at midnight Pacific Time.
+Usage is recorded per OAuth client id, so only executions with the same --client-secret are counted.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{NOLOGIN: "true"},
+		Run: func(c *cobra.Command, _ []string) {
+			secret, _ := c.Flags().GetString("client-secret")
+			file, _ := c.Flags().GetString("quota-ledger")
+			limit, _ := c.Flags().GetUint32("quota-limit")
+
+			client := bigg.NewClient()
+			if err := client.SetSecretFromFile(secret); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			ledger, err := bigg.LoadLedger(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			day := bigg.QuotaDay(time.Now())
+			used, methods := ledger.Used(client.ClientID, day)
+
+			names := make([]string, 0, len(methods))
+			for m := range methods {
+				names = append(names, m)
+			}
+			sort.Strings(names)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(w, "Quota day\t%s\n", day)
+			for _, m := range names {
+				fmt.Fprintf(w, "%s\t%d\n", m, methods[m])
+			}
+			fmt.Fprintf(w, "Used\t%d\n", used)
+			fmt.Fprintf(w, "Remaining\t%d\n", ledger.Remaining(client.ClientID, day, limit))
+			if err := w.Flush(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/raffaelecassia/youtubetoolkit"
 	"github.com/raffaelecassia/youtubetoolkit/bigg"
@@ -13,54 +16,181 @@ func Root(tk *youtubetoolkit.Toolkit) *cobra.Command {
 	var clientSecretFile string
 	var tokenFile string
 	var debug bool
+	var quotaBudget uint32
+	var quotaTruncate bool
+	var quotaLedger string
+	var quotaLimit uint32
+	var quotaEnforce bool
+	var retries int
+	var retryMaxWait time.Duration
+	var noCache bool
+	var noETags bool
+	var cacheFile string
+	var cacheTTL youtubetoolkit.CacheTTL
+	var where []string
+	var sortBy string
+	var limit int
+
+	var clientID string
 
 	var ytsvc *bigg.Youtube
+	var dryrun *youtubetoolkit.DryRunService
+	var cache *youtubetoolkit.Cache
+	var cachedsvc *youtubetoolkit.CachedService
 
 	cmd := &cobra.Command{
 		Use:   "youtubetoolkit",
 		Short: "A toolkit for Youtube",
 		PersistentPreRun: func(c *cobra.Command, _ []string) {
-			// skip for help and completion commands (and hidden ones like __complete)
-			if c.Use[:4] == "help" || (c.HasParent() && c.Parent().Use == "completion") || c.Use[:2] == "__" {
+			if skipLogin(c) {
 				return
 			}
+			itemType, printsItems := itemTypes[c.Annotations[ITEMS]]
+			if (len(where) > 0 || sortBy != "") && !printsItems {
+				fmt.Fprintln(os.Stderr, "Error: --where and --sort-by apply only to the commands printing items")
+				os.Exit(1)
+			}
+			for _, w := range where {
+				expr, err := youtubetoolkit.ParseExpr(w, itemType)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					os.Exit(1)
+				}
+				outputOptions = append(outputOptions, youtubetoolkit.Where(expr))
+			}
+			if sortBy != "" {
+				keys, err := youtubetoolkit.ParseSortKeys(sortBy, itemType)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					os.Exit(1)
+				}
+				outputOptions = append(outputOptions, youtubetoolkit.SortBy(keys...))
+			}
+			if limit < 0 {
+				fmt.Fprintln(os.Stderr, "Error: --limit must be positive")
+				os.Exit(1)
+			} else if limit > 0 {
+				outputOptions = append(outputOptions, youtubetoolkit.Limit(limit))
+			}
 			//
 			// login
 			//
-			client := bigg.NewClient()
-			client.TokenFile = tokenFile
-
-			if debug {
-				client.EnableLogTransport()
+			// commands acting on another account (eg. subscriptions sync --to) override --token
+			if to, err := c.Flags().GetString("to"); err == nil && to != "" {
+				tokenFile = to
 			}
-
-			if err := client.SetSecretFromFile(clientSecretFile); err != nil {
+			svc, id, err := login(clientSecretFile, tokenFile, debug, bigg.RetryPolicy{
+				MaxRetries: retries,
+				BaseDelay:  bigg.DefaultRetryPolicy.BaseDelay,
+				MaxDelay:   retryMaxWait,
+			})
+			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
 			}
 
-			if err := client.Authorize(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
+			var service youtubetoolkit.YoutubeService = svc
+			ytsvc = svc
+			clientID = id
+
+			if !noETags {
+				if err := loadETagStore(svc, tokenFile); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
 			}
 
-			svc, err := client.NewYoutubeService()
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
+			if !noCache && cacheFile != "" {
+				cache, err = youtubetoolkit.LoadCache(cacheFile)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				cachedsvc = youtubetoolkit.NewCachedService(service, cache, cacheTTL)
+				service = cachedsvc
 			}
 
-			tk.SetService(svc)
-			ytsvc = svc
+			if dry, err := c.Flags().GetBool("dry-run"); err == nil && dry {
+				dryrun = youtubetoolkit.NewDryRunService(service)
+				service = dryrun
+			}
+			tk.SetService(service)
+
+			//
+			// quota budget, from flags and from the daily quota left
+			//
+			var budget uint32
+			var policy youtubetoolkit.BudgetPolicy
+			var limited bool
+			if quotaBudget > 0 {
+				budget, policy, limited = quotaBudget, youtubetoolkit.BudgetRefuse, true
+				if quotaTruncate {
+					policy = youtubetoolkit.BudgetTruncate
+				}
+			}
+			// without a budget, the daily quota left is a warning (or a refusal with --quota-enforce)
+			if quotaLedger != "" {
+				ledger, err := bigg.LoadLedger(quotaLedger)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				remaining := ledger.Remaining(clientID, bigg.QuotaDay(time.Now()), quotaLimit)
+				if !limited || remaining < budget {
+					budget, policy, limited = remaining, youtubetoolkit.BudgetWarn, true
+					if quotaEnforce {
+						policy = youtubetoolkit.BudgetRefuse
+					}
+				}
+			}
+			if limited {
+				tk.SetQuotaBudget(budget, policy)
+			}
 
 			tk.SetLogWriter(os.Stderr)
 		},
 		PersistentPostRun: func(c *cobra.Command, _ []string) {
-			// skip for help and completion commands (and hidden ones like __complete)
-			if c.Use[:4] == "help" || (c.HasParent() && c.Parent().Use == "completion") || c.Use[:2] == "__" {
+			if skipLogin(c) {
 				return
 			}
-			fmt.Fprintln(os.Stderr, "Quota cost:", ytsvc.GetCost(), "units")
+			if dryrun != nil {
+				for _, op := range dryrun.Operations() {
+					fmt.Fprintf(os.Stderr, "[dry-run] would %s %s (%d units)\n", op.Action, op.Target, op.Cost)
+				}
+				fmt.Fprintln(os.Stderr, "Projected quota cost:", dryrun.GetCost(), "units")
+			}
+			if cache != nil {
+				if hits, misses := cachedsvc.Counts(); hits+misses > 0 {
+					fmt.Fprintf(os.Stderr, "Cache: %d hits, %d misses\n", hits, misses)
+				}
+				if err := cache.Save(); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+			}
+			for _, store := range etagStores {
+				if err := store.Save(); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+			}
+			// all the accounts spend the quota of the same GCloud project
+			services := append([]*bigg.Youtube{ytsvc}, otherAccounts...)
+			var cost uint32
+			for _, svc := range services {
+				cost += svc.GetCost()
+			}
+			fmt.Fprintln(os.Stderr, "Quota cost:", cost, "units")
+
+			if quotaLedger != "" && cost > 0 {
+				// reloads the ledger, it may have been updated by another execution
+				err := bigg.UpdateLedger(quotaLedger, func(ledger *bigg.Ledger) {
+					for _, svc := range services {
+						ledger.Add(clientID, bigg.QuotaDay(time.Now()), svc.GetCostByMethod())
+					}
+				})
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+			}
 		},
 	}
 
@@ -68,6 +198,27 @@ func Root(tk *youtubetoolkit.Toolkit) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&clientSecretFile, "client-secret", "s", "client_secret.json", "OAuth2 client secret JSON file")
 	cmd.PersistentFlags().StringVarP(&tokenFile, "token", "t", "goauth.token", "login token filename")
 	cmd.PersistentFlags().BoolVarP(&debug, "debug-http", "d", false, "logs to stdout each http request/response")
+	cmd.PersistentFlags().Uint32Var(&quotaBudget, "quota-budget", 0, "max quota units to spend (0 means no limit). Commands exceeding the estimated cost are refused")
+	cmd.PersistentFlags().BoolVar(&quotaTruncate, "quota-budget-truncate", false, "with --quota-budget, processes only the inputs that fit the budget instead of refusing")
+	cmd.PersistentFlags().IntVar(&retries, "retries", bigg.DefaultRetryPolicy.MaxRetries, "max retries of an api call failing with a transient error (0 disables retries)")
+	cmd.PersistentFlags().DurationVar(&retryMaxWait, "retry-max-wait", bigg.DefaultRetryPolicy.MaxDelay, "max wait between retries (a Retry-After from the server is always respected)")
+
+	defaultLedgerFile, _ := bigg.DefaultLedgerFile()
+	cmd.PersistentFlags().StringVar(&quotaLedger, "quota-ledger", defaultLedgerFile, "file where the daily quota usage is recorded (empty to disable)")
+	cmd.PersistentFlags().Uint32Var(&quotaLimit, "quota-limit", bigg.DAILY_QUOTA, "daily quota of the GCloud project")
+	cmd.PersistentFlags().BoolVar(&quotaEnforce, "quota-enforce", false, "refuses commands exceeding the daily quota left (default is a warning)")
+
+	defaultCacheFile, _ := youtubetoolkit.DefaultCacheFile()
+	cmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "doesn't read nor write the cache of channels, playlists and videos metadata")
+	cmd.PersistentFlags().StringVar(&cacheFile, "cache-file", defaultCacheFile, "cache file of channels, playlists and videos metadata")
+	cmd.PersistentFlags().DurationVar(&cacheTTL.Channels, "cache-ttl-channels", youtubetoolkit.DefaultCacheTTL.Channels, "max age of the cached channels metadata (0 disables)")
+	cmd.PersistentFlags().DurationVar(&cacheTTL.Playlists, "cache-ttl-playlists", youtubetoolkit.DefaultCacheTTL.Playlists, "max age of the cached playlists metadata (0 disables)")
+	cmd.PersistentFlags().DurationVar(&cacheTTL.Videos, "cache-ttl-videos", youtubetoolkit.DefaultCacheTTL.Videos, "max age of the cached videos metadata, statistics included (0 disables)")
+	cmd.PersistentFlags().BoolVar(&noETags, "no-etags", false, "doesn't send conditional requests for the lists (the ETags are stored in the token filename + \".etags\")")
+
+	cmd.PersistentFlags().StringArrayVar(&where, "where", nil, `outputs only the items matching the expression (eg. 'VideoTitle ~ "(?i)podcast"', see README), repeatable`)
+	cmd.PersistentFlags().StringVar(&sortBy, "sort-by", "", `sorts the output by the fields, with ":desc" for descending order (eg. "ViewCount:desc,PublishedAt")`)
+	cmd.PersistentFlags().IntVar(&limit, "limit", 0, "outputs at most this number of items (without --sort-by, lists stop loading pages when reached)")
 
 	cmd.PersistentFlags().Bool("csv", true, "CSV output")
 	cmd.PersistentFlags().Bool("table", false, "Table output")
@@ -88,23 +239,159 @@ func Execute() error {
 	_ = SubscriptionsList(subs, tk)
 	_ = Subscribe(subs, tk)
 	_ = Unsubscribe(subs, tk)
+	_ = SubscriptionsDiff(subs, tk)
+	_ = SyncSubscriptions(subs, tk)
 
 	pls := Playlists(root, tk)
 	_ = NewPlaylist(pls, tk)
+	_ = UpdatePlaylist(pls, tk)
+	_ = ClonePlaylist(pls, tk)
 	_ = DelPlaylist(pls, tk)
 
 	pl := Playlist(root, tk)
 	_ = AddToPlaylist(pl, tk)
+	_ = RemoveFromPlaylist(pl, tk)
+	_ = SyncPlaylist(pl, tk)
+	_ = SortPlaylist(pl, tk)
 
 	_ = LastUploads(root, tk)
 
-	return root.Execute()
+	quota := Quota(root, tk)
+	_ = QuotaStatus(quota, tk)
+
+	cache := Cache(root, tk)
+	_ = CacheStats(cache, tk)
+	_ = CacheClear(cache, tk)
+
+	// cancels running flows on ctrl-c
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return root.ExecuteContext(ctx)
 }
 
 //
 // utils
 //
 
+// skipLogin returns true for the commands that don't call the API: help and
+// completion commands (and hidden ones like __complete) or annotated with NOLOGIN.
+func skipLogin(c *cobra.Command) bool {
+	return c.Use[:4] == "help" || (c.HasParent() && c.Parent().Use == "completion") || c.Use[:2] == "__" ||
+		c.Annotations[NOLOGIN] == "true"
+}
+
+// NOLOGIN is the annotation of the commands that don't need the login.
+const NOLOGIN = "nologin"
+
+// ITEMS is the annotation of the commands printing items, with their type (a key of itemTypes):
+// the fields of --where and --sort-by are checked against it.
+const ITEMS = "items"
+
+var itemTypes = map[string]youtubetoolkit.ItemType{
+	"subscriptions": youtubetoolkit.SubscriptionItem,
+	"playlists":     youtubetoolkit.PlaylistItem,
+	"videos":        youtubetoolkit.PlaylistVideoItem,
+}
+
+// login authorizes a token file (starting the OAuth2 flow if needed) and returns
+// the youtube service and the OAuth2 client ID.
+func login(clientSecretFile, tokenFile string, debug bool, retryPolicy bigg.RetryPolicy) (*bigg.Youtube, string, error) {
+	client := bigg.NewClient()
+	client.TokenFile = tokenFile
+
+	if debug {
+		client.EnableLogTransport()
+	}
+
+	if err := client.SetSecretFromFile(clientSecretFile); err != nil {
+		return nil, "", err
+	}
+
+	if err := client.Authorize(); err != nil {
+		return nil, "", err
+	}
+
+	svc, err := client.NewYoutubeService()
+	if err != nil {
+		return nil, "", err
+	}
+	svc.SetRetryPolicy(retryPolicy)
+	return svc, client.ClientID, nil
+}
+
+// otherAccounts are the services of the accounts logged in by loginAccount.
+var otherAccounts []*bigg.Youtube
+
+// loginAccount logs in a second account (eg. subscriptions diff --with), with the
+// same global flags of the main one, and returns a Toolkit for it.
+// Its quota cost is added to the main account's one.
+func loginAccount(c *cobra.Command, tokenFile string) (*youtubetoolkit.Toolkit, error) {
+	clientSecretFile, _ := c.Flags().GetString("client-secret")
+	debug, _ := c.Flags().GetBool("debug-http")
+	retries, _ := c.Flags().GetInt("retries")
+	retryMaxWait, _ := c.Flags().GetDuration("retry-max-wait")
+	svc, _, err := login(clientSecretFile, tokenFile, debug, bigg.RetryPolicy{
+		MaxRetries: retries,
+		BaseDelay:  bigg.DefaultRetryPolicy.BaseDelay,
+		MaxDelay:   retryMaxWait,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("login with %s: %w", tokenFile, err)
+	}
+	if noETags, _ := c.Flags().GetBool("no-etags"); !noETags {
+		if err := loadETagStore(svc, tokenFile); err != nil {
+			return nil, err
+		}
+	}
+	otherAccounts = append(otherAccounts, svc)
+	return youtubetoolkit.NewWithService(svc), nil
+}
+
+// etagStores are the ETag stores of the logged in accounts, saved after the command.
+var etagStores []*bigg.ETagStore
+
+// loadETagStore enables the conditional list requests of an account,
+// with the ETags stored next to its token file.
+func loadETagStore(svc *bigg.Youtube, tokenFile string) error {
+	store, err := bigg.LoadETagStore(tokenFile + ".etags")
+	if err != nil {
+		return err
+	}
+	svc.SetETagStore(store)
+	etagStores = append(etagStores, store)
+	return nil
+}
+
+// addDryRunFlag adds the --dry-run flag to a command that makes changes.
+// The flag is handled by the root command.
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "shows what would be changed and the projected quota cost, without changing anything")
+}
+
+// addJournalFlags adds the --journal and --resume flags to a command running a resumable flow.
+func addJournalFlags(cmd *cobra.Command) {
+	cmd.Flags().String("journal", "", "records to this file the outcome of each input (done, failed or skipped)")
+	cmd.Flags().String("resume", "", "resumes from this journal file: inputs already done are skipped, new outcomes are appended")
+	cmd.MarkFlagsMutuallyExclusive("journal", "resume")
+}
+
+// journalFromFlags opens the journal file set by --journal or --resume.
+// Returns a nil journal if none is set. A dry-run can't record a journal: its writes are not done.
+func journalFromFlags(c *cobra.Command) (*youtubetoolkit.Journal, error) {
+	if dry, err := c.Flags().GetBool("dry-run"); err == nil && dry &&
+		(c.Flags().Changed("journal") || c.Flags().Changed("resume")) {
+		return nil, fmt.Errorf("--journal and --resume can't be used with --dry-run")
+	}
+	if file, err := c.Flags().GetString("resume"); err == nil && file != "" {
+		return youtubetoolkit.OpenJournal(file, true)
+	}
+	if file, err := c.Flags().GetString("journal"); err == nil && file != "" {
+		return youtubetoolkit.OpenJournal(file, false)
+	}
+	return nil, nil
+}
+
 func checkStdinInput() bool {
 	stat, err := os.Stdin.Stat()
 	if err != nil {
@@ -117,10 +404,14 @@ func checkStdinInput() bool {
 }
 
 var (
-	DEFAULT_FIELDS_SUBSCRIPTIONS    = &[]string{"ChannelId", "ChannelTitle", "ChannelUrl", "ChannelThumbUrl"}
-	DEFAULT_FIELDS_UPLOADS_PLAYLIST = &[]string{"VideoId", "VideoTitle", "PublishedAt", "ChannelId", "ChannelTitle"}
-	DEFAULT_FIELDS_PLAYLISTS        = &[]string{"PlaylistId", "PlaylistTitle", "VideoCount"}
-	DEFAULT_FIELDS_PLAYLIST         = &[]string{"VideoId", "VideoTitle", "VideoUrl", "ChannelId", "ChannelTitle", "ChannelUrl"}
+	DEFAULT_FIELDS_SUBSCRIPTIONS      = &[]string{"ChannelId", "ChannelTitle", "ChannelUrl", "ChannelThumbUrl"}
+	DEFAULT_FIELDS_SUBSCRIBE_SKIP     = &[]string{"ChannelId", "ChannelTitle", "ChannelUrl", "ChannelThumbUrl", "Status"}
+	DEFAULT_FIELDS_SUBSCRIPTIONS_DIFF = &[]string{"ChannelId", "ChannelTitle", "ChannelUrl", "Status"}
+	DEFAULT_FIELDS_UPLOADS_PLAYLIST   = &[]string{"VideoId", "VideoTitle", "PublishedAt", "ChannelId", "ChannelTitle"}
+	DEFAULT_FIELDS_PLAYLISTS          = &[]string{"PlaylistId", "PlaylistTitle", "VideoCount"}
+	DEFAULT_FIELDS_PLAYLIST           = &[]string{"VideoId", "VideoTitle", "VideoUrl", "ChannelId", "ChannelTitle", "ChannelUrl"}
+	DEFAULT_FIELDS_PLAYLIST_ADD_SKIP  = &[]string{"VideoId", "VideoTitle", "VideoUrl", "ChannelId", "ChannelTitle", "ChannelUrl", "Status"}
+	DEFAULT_FIELDS_PLAYLIST_SYNC      = &[]string{"VideoId", "VideoTitle", "Status", "Position"}
 )
 
 func outputFromFlags(c *cobra.Command, defaultfields *[]string) youtubetoolkit.FlowOption {
@@ -129,9 +420,17 @@ func outputFromFlags(c *cobra.Command, defaultfields *[]string) youtubetoolkit.F
 		fields = *defaultfields
 	}
 	if val, err := c.Flags().GetBool("table"); val && err == nil {
-		return youtubetoolkit.TableSink(os.Stdout, &fields)
+		return withOutputOptions(youtubetoolkit.TableSink(os.Stdout, &fields))
 	} else if val, err := c.Flags().GetBool("jsonl"); val && err == nil {
-		return youtubetoolkit.JSONLinesSink(os.Stdout)
+		return withOutputOptions(youtubetoolkit.JSONLinesSink(os.Stdout))
 	}
-	return youtubetoolkit.CSVSink(os.Stdout, &fields)
+	return withOutputOptions(youtubetoolkit.CSVSink(os.Stdout, &fields))
+}
+
+// outputOptions are the options of the outputs from the global flags (eg. --where).
+var outputOptions []youtubetoolkit.FlowOption
+
+// withOutputOptions adds the outputOptions to a sink.
+func withOutputOptions(sink youtubetoolkit.FlowOption) youtubetoolkit.FlowOption {
+	return youtubetoolkit.ComposeOptions(append([]youtubetoolkit.FlowOption{sink}, outputOptions...)...)
 }
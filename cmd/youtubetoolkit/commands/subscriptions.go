@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/raffaelecassia/youtubetoolkit"
@@ -19,49 +20,83 @@ func Subscriptions(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Com
 }
 
 func SubscriptionsList(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var outputFormat string
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "Returns all channels from user subscriptions",
 		Long: `Returns all channels from user subscriptions.
 Available fields for CSV/Table output: ChannelId*, ChannelTitle*, ChannelUrl*, ChannelThumbUrl*, SubscriptionId.
-(* default fields when --fields is not specified.)`,
-		Args: cobra.NoArgs,
+(* default fields when --fields is not specified.)
+With --output-format, writes a subscriptions file for RSS readers (opml), Google Takeout (takeout)
+or alternative frontends (newpipe, freetube, invidious).`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "subscriptions"},
 		Run: func(c *cobra.Command, _ []string) {
-			err := tk.Subscriptions(
-				outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIPTIONS))
+			output, err := subscriptionsOutputFromFlags(c, outputFormat)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			err = tk.SubscriptionsContext(c.Context(), output)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
 		},
 	}
+	cmd.Flags().StringVar(&outputFormat, "output-format", "", "subscriptions file format to write instead of the --csv/--table/--jsonl output: "+SUBSCRIPTIONS_FORMATS)
 	parent.AddCommand(cmd)
 	return cmd
 }
 
 func Subscribe(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
 	var print bool
+	var skipExisting bool
+	var inputFormat string
 	cmd := &cobra.Command{
 		Use:   "add [channel id]",
 		Short: "Subscribe to a channel",
 		Long: `Subscribe to a channel.
-To add multiple channels, send to stdin a list of channel ids (or a CSV with ids in the first column).
-If --print-data flag is used, the default fields from command subscriptions-list will apply.`,
-		Args: cobra.MaximumNArgs(1),
+To add multiple channels, send to stdin a list of channel ids (or a CSV with ids in the first column),
+or a subscriptions file in the format set by --input-format: opml (RSS readers), takeout (the
+Google Takeout subscriptions.csv), newpipe, freetube (the profiles .db export) or invidious.
+If --print-data flag is used, the default fields from command subscriptions-list will apply
+(plus the Status field, "added" or "skipped", when --skip-existing is used).
+With --skip-existing, the current subscriptions are loaded first (1 unit every 50 channels) and
+channels already subscribed are skipped instead of paying 50 units each.`,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{ITEMS: "subscriptions"},
 		Run: func(c *cobra.Command, args []string) {
 			var output youtubetoolkit.FlowOption
-			if print {
+			if print && skipExisting {
+				output = outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIBE_SKIP)
+			} else if print {
 				output = outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIPTIONS)
 			} else {
 				output = youtubetoolkit.NullSink()
 			}
+			journal, err := journalFromFlags(c)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			defer journal.Close()
+			opts := []youtubetoolkit.FlowOption{output, youtubetoolkit.WithJournal(journal)}
+			if skipExisting {
+				opts = append(opts, youtubetoolkit.SkipExisting())
+			}
 			if len(args) == 1 {
-				err := tk.Subscribe(youtubetoolkit.SingleStringSource(args[0]), output)
+				err := tk.SubscribeContext(c.Context(), append(opts, youtubetoolkit.SingleStringSource(args[0]))...)
 				if err != nil {
 					fmt.Fprintln(os.Stderr, "Error:", err)
 				}
 			} else {
 				if checkStdinInput() {
-					err := tk.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(os.Stdin), output)
+					source, err := subscriptionsSourceFromFlags(inputFormat, os.Stdin)
+					if err != nil {
+						fmt.Fprintln(os.Stderr, "Error:", err)
+						return
+					}
+					err = tk.SubscribeContext(c.Context(), append(opts, source)...)
 					if err != nil {
 						fmt.Fprintln(os.Stderr, "Error:", err)
 					}
@@ -76,6 +111,10 @@ If --print-data flag is used, the default fields from command subscriptions-list
 		},
 	}
 	cmd.Flags().BoolVarP(&print, "print-data", "p", false, "print to stdout the subscriptions infos of the added channel(s)")
+	cmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "skips channels already subscribed (and duplicates in input)")
+	cmd.Flags().StringVar(&inputFormat, "input-format", "csv", "format of the stdin input: csv, "+SUBSCRIPTIONS_FORMATS)
+	addDryRunFlag(cmd)
+	addJournalFlags(cmd)
 	parent.AddCommand(cmd)
 	return cmd
 }
@@ -86,12 +125,132 @@ func Unsubscribe(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Comma
 		Short: "Unsubscribe from a channel",
 		Args:  cobra.ExactArgs(1),
 		Run: func(c *cobra.Command, args []string) {
-			err := tk.Unsubscribe(args[0])
+			err := tk.UnsubscribeContext(c.Context(), args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	addDryRunFlag(cmd)
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func SubscriptionsDiff(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var with string
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compares the subscriptions of two accounts",
+		Long: `Compares the subscriptions of the account logged in with --token (A)
+with the ones of the account logged in with --with (B).
+Prints to stdout all the channels, with the Status field "only-a", "only-b" or "common".
+Default fields: ChannelId, ChannelTitle, ChannelUrl, Status.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "subscriptions"},
+		Run: func(c *cobra.Command, _ []string) {
+			other, err := loginAccount(c, with)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			err = tk.SubscriptionsDiffContext(c.Context(), other,
+				outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIPTIONS_DIFF))
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
 		},
 	}
+	cmd.Flags().StringVar(&with, "with", "", "login token filename of the account to compare with, mandatory")
+	err := cmd.MarkFlagRequired("with")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+func SyncSubscriptions(parent *cobra.Command, tk *youtubetoolkit.Toolkit) *cobra.Command {
+	var from string
+	var prune bool
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Subscribes an account to the channels of another one",
+		Long: `Subscribes the account logged in with --to (or --token) to the channels
+subscribed by the account logged in with --from and not by the first one.
+With --prune, the subscriptions not in the --from account are removed too,
+so that the two accounts end up with the same subscriptions.
+The quota cost of the plan (50 units for each subscription and each removal)
+is printed before applying it.
+Prints to stdout the changes, with the Status field "added" or "deleted".
+Default fields: ChannelId, ChannelTitle, ChannelUrl, Status.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{ITEMS: "subscriptions"},
+		Run: func(c *cobra.Command, _ []string) {
+			source, err := loginAccount(c, from)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return
+			}
+			opts := []youtubetoolkit.FlowOption{outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIPTIONS_DIFF)}
+			if prune {
+				opts = append(opts, youtubetoolkit.Prune())
+			}
+			err = tk.SyncSubscriptionsContext(c.Context(), source, opts...)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "login token filename of the account to copy the subscriptions from, mandatory")
+	cmd.Flags().String("to", "", "login token filename of the account to change (default is --token)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "also removes the subscriptions not in the --from account")
+	err := cmd.MarkFlagRequired("from")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	addDryRunFlag(cmd)
 	parent.AddCommand(cmd)
 	return cmd
 }
+
+// SUBSCRIPTIONS_FORMATS are the subscriptions file formats of --input-format and --output-format.
+const SUBSCRIPTIONS_FORMATS = "opml, takeout, newpipe, freetube, invidious"
+
+// subscriptionsSourceFromFlags returns the source reading input in the --input-format format.
+func subscriptionsSourceFromFlags(format string, input io.Reader) (youtubetoolkit.FlowOption, error) {
+	switch format {
+	case "csv":
+		return youtubetoolkit.CSVFirstFieldOnlySource(input), nil
+	case "opml":
+		return youtubetoolkit.OPMLSource(input), nil
+	case "takeout":
+		return youtubetoolkit.TakeoutSource(input), nil
+	case "newpipe":
+		return youtubetoolkit.NewPipeSource(input), nil
+	case "freetube":
+		return youtubetoolkit.FreeTubeSource(input), nil
+	case "invidious":
+		return youtubetoolkit.InvidiousSource(input), nil
+	}
+	return nil, fmt.Errorf("unknown input format %q", format)
+}
+
+// subscriptionsOutputFromFlags returns the sink writing to stdout in the --output-format
+// format, or the usual --csv/--table/--jsonl output if no format is set.
+func subscriptionsOutputFromFlags(c *cobra.Command, format string) (youtubetoolkit.FlowOption, error) {
+	switch format {
+	case "":
+		return outputFromFlags(c, DEFAULT_FIELDS_SUBSCRIPTIONS), nil
+	case "opml":
+		return withOutputOptions(youtubetoolkit.OPMLSink(os.Stdout)), nil
+	case "takeout":
+		return withOutputOptions(youtubetoolkit.TakeoutSink(os.Stdout)), nil
+	case "newpipe":
+		return withOutputOptions(youtubetoolkit.NewPipeSink(os.Stdout)), nil
+	case "freetube":
+		return withOutputOptions(youtubetoolkit.FreeTubeSink(os.Stdout)), nil
+	case "invidious":
+		return withOutputOptions(youtubetoolkit.InvidiousSink(os.Stdout)), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
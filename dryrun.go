@@ -0,0 +1,188 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+	"google.golang.org/api/youtube/v3"
+)
+
+// DryRunService is a YoutubeService that forwards read operations to the wrapped
+// service and records write operations instead of sending them to the API.
+// Writes return placeholder resources built from their input, so flows run as usual.
+type DryRunService struct {
+	YoutubeService
+
+	mu   sync.Mutex
+	ops  []DryRunOp
+	cost uint32
+}
+
+// DryRunOp is a write operation recorded by a DryRunService.
+type DryRunOp struct {
+	// Action is a human readable description of the operation (eg. "subscribe to")
+	Action string
+	// Target is the ID of the resource created or deleted
+	Target string
+	// Cost is the quota impact the operation would have had
+	Cost uint32
+}
+
+// DRYRUN_PLAYLIST_ID is the ID of the playlists created by a DryRunService.
+const DRYRUN_PLAYLIST_ID = "DRYRUN-PLAYLIST"
+
+func NewDryRunService(svc YoutubeService) *DryRunService {
+	return &DryRunService{YoutubeService: svc}
+}
+
+// Operations returns the write operations recorded so far.
+func (d *DryRunService) Operations() []DryRunOp {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DryRunOp{}, d.ops...)
+}
+
+// GetCost returns the projected quota cost: the cost of the read operations
+// made by the wrapped service plus the cost of the recorded writes.
+func (d *DryRunService) GetCost() uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cost := d.cost
+	if qc, ok := d.YoutubeService.(QuotaCounter); ok {
+		cost += qc.GetCost()
+	}
+	return cost
+}
+
+func (d *DryRunService) record(action, target string, cost uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ops = append(d.ops, DryRunOp{action, target, cost})
+	d.cost += cost
+}
+
+// SubscriptionInsert implements YoutubeService
+func (d *DryRunService) SubscriptionInsert(ctx context.Context, channelId string) (*bigg.Sub, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.record("subscribe to channel", channelId, bigg.QUOTA_COST_INSERT)
+	return &bigg.Sub{Subscription: &youtube.Subscription{
+		Snippet: &youtube.SubscriptionSnippet{
+			Title: channelId,
+			ResourceId: &youtube.ResourceId{
+				Kind:      "youtube#channel",
+				ChannelId: channelId,
+			},
+			Thumbnails: &youtube.ThumbnailDetails{Default: &youtube.Thumbnail{}},
+		},
+	}}, nil
+}
+
+// SubscriptionDelete implements YoutubeService
+func (d *DryRunService) SubscriptionDelete(ctx context.Context, channelId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.record("unsubscribe from channel", channelId, bigg.QUOTA_COST_SUBSCRIPTION_DELETE)
+	return nil
+}
+
+// SubscriptionDeleteById implements YoutubeService
+func (d *DryRunService) SubscriptionDeleteById(ctx context.Context, subscriptionId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.record("delete subscription", subscriptionId, bigg.QUOTA_COST_DELETE)
+	return nil
+}
+
+// PlaylistInsert implements YoutubeService
+func (d *DryRunService) PlaylistInsert(ctx context.Context, meta bigg.PlaylistMetadata) (*bigg.Playlist, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.record("create playlist", meta.Title, bigg.QUOTA_COST_INSERT)
+	return &bigg.Playlist{Playlist: &youtube.Playlist{
+		Id:             DRYRUN_PLAYLIST_ID,
+		Snippet:        &youtube.PlaylistSnippet{Title: meta.Title, Description: meta.Description},
+		Status:         &youtube.PlaylistStatus{PrivacyStatus: meta.PrivacyStatus},
+		ContentDetails: &youtube.PlaylistContentDetails{},
+	}}, nil
+}
+
+// PlaylistUpdate implements YoutubeService
+// The playlist is read to check the update, the write is recorded.
+func (d *DryRunService) PlaylistUpdate(ctx context.Context, playlistId string, update func(*bigg.PlaylistMetadata) error) (*bigg.Playlist, error) {
+	current, err := d.YoutubeService.PlaylistInfo(ctx, playlistId)
+	if err != nil {
+		return nil, err
+	}
+	meta := bigg.PlaylistMetadataOf(current)
+	if err := update(&meta); err != nil {
+		return nil, err
+	}
+	d.record("update playlist", playlistId, bigg.QUOTA_COST_UPDATE)
+	return current, nil
+}
+
+// PlaylistDelete implements YoutubeService
+func (d *DryRunService) PlaylistDelete(ctx context.Context, playlistId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.record("delete playlist", playlistId, bigg.QUOTA_COST_DELETE)
+	return nil
+}
+
+// PlaylistItemsInsert implements YoutubeService
+func (d *DryRunService) PlaylistItemsInsert(ctx context.Context, playlistId, videoId string, position int64) (*bigg.PlaylistItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if position == bigg.POSITION_APPEND {
+		d.record("add to playlist "+playlistId+" video", videoId, bigg.QUOTA_COST_INSERT)
+	} else {
+		d.record(fmt.Sprintf("add at position %d to playlist %s video", position, playlistId), videoId, bigg.QUOTA_COST_INSERT)
+	}
+	return &bigg.PlaylistItem{PlaylistItem: &youtube.PlaylistItem{
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistId,
+			Position:   position,
+			ResourceId: &youtube.ResourceId{
+				Kind:    "youtube#video",
+				VideoId: videoId,
+			},
+		},
+	}}, nil
+}
+
+// PlaylistItemsDelete implements YoutubeService
+func (d *DryRunService) PlaylistItemsDelete(ctx context.Context, playlistItemId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.record("delete playlist item", playlistItemId, bigg.QUOTA_COST_DELETE)
+	return nil
+}
+
+// PlaylistItemsUpdate implements YoutubeService
+func (d *DryRunService) PlaylistItemsUpdate(ctx context.Context, playlistItemId, playlistId, videoId string, position int64) (*bigg.PlaylistItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.record(fmt.Sprintf("move to position %d in playlist %s video", position, playlistId), videoId, bigg.QUOTA_COST_UPDATE)
+	return &bigg.PlaylistItem{PlaylistItem: &youtube.PlaylistItem{
+		Id: playlistItemId,
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistId,
+			Position:   position,
+			ResourceId: &youtube.ResourceId{
+				Kind:    "youtube#video",
+				VideoId: videoId,
+			},
+		},
+	}}, nil
+}
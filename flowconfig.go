@@ -1,25 +1,134 @@
 package youtubetoolkit
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
 )
 
 type FlowOption func(*flowconfig)
 
 type flowconfig struct {
-	stringSource func(errors chan<- error) <-chan string
-	itemSink     func(errors chan<- error, input <-chan Item)
+	stringSource func(ctx context.Context, errors chan<- error) <-chan string
+	itemSink     func(ctx context.Context, errors chan<- error, input <-chan Item)
+	journal      *Journal
+	skipExisting bool
+	prune        bool
+	videoDetails bool
+	exclude      map[VideoKind]bool
+	minDuration  time.Duration
+	maxDuration  time.Duration
+	where        []func(Item) bool
+	sortBy       []SortKey
+	limit        int
+	limiter      *limiter
+	insertAt     *int64
+}
+
+// SkipExisting makes the flows adding things skip what already exists (eg. channels
+// already subscribed or videos already in the playlist) and duplicates in input.
+// Skipped inputs are sent to the sink with status "skipped".
+func SkipExisting() FlowOption {
+	return func(ic *flowconfig) {
+		ic.skipExisting = true
+	}
+}
+
+// Prune makes the sync flows also remove what is not in the source of the sync
+// (eg. subscriptions not in the other account).
+func Prune() FlowOption {
+	return func(ic *flowconfig) {
+		ic.prune = true
+	}
+}
+
+// VideoDetails makes the flows listing videos (eg. LastUploads) also look up the video
+// details: Duration, DurationSeconds, ViewCount, LikeCount, LiveBroadcastContent and Definition.
+// Videos are looked up 50 at a time (1 unit every 50 videos).
+func VideoDetails() FlowOption {
+	return func(ic *flowconfig) {
+		ic.videoDetails = true
+	}
+}
+
+// InsertAt makes AddVideoToPlaylist insert the videos from a zero based position of the
+// playlist (0, the top, is the default), in input order, or append them with bigg.POSITION_APPEND.
+func InsertAt(position int64) FlowOption {
+	return func(ic *flowconfig) {
+		ic.insertAt = &position
+	}
+}
+
+// SortKey is a field of the Items to sort by (see SortBy).
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSortKeys parses a comma separated list of sort keys: field names of the Items of type it,
+// optionally followed by ":asc" (the default) or ":desc" (eg. "ViewCount:desc,PublishedAt").
+func ParseSortKeys(s string, it ItemType) ([]SortKey, error) {
+	fields := it.fields()
+	keys := []SortKey{}
+	for _, k := range strings.Split(s, ",") {
+		field, order, _ := strings.Cut(strings.TrimSpace(k), ":")
+		if field == "" {
+			continue
+		}
+		if !fields[field] {
+			return nil, fmt.Errorf("sort by: unknown field %q (fields: %s)", field, strings.Join(it.Fields(), ", "))
+		}
+		switch order {
+		case "", "asc":
+			keys = append(keys, SortKey{field, false})
+		case "desc":
+			keys = append(keys, SortKey{field, true})
+		default:
+			return nil, fmt.Errorf("sort by: unknown order %q of %s (asc or desc)", order, field)
+		}
+	}
+	return keys, nil
+}
+
+// SortBy makes the flow send the Items to the sink sorted by the keys (after buffering all of them).
+// Numeric fields (eg. VideoCount) are compared as numbers, the others as strings.
+func SortBy(keys ...SortKey) FlowOption {
+	return func(ic *flowconfig) {
+		ic.sortBy = append(ic.sortBy, keys...)
+	}
+}
+
+// Limit makes the flow send at most n Items to the sink (after Where and SortBy), zero means no limit.
+// Without SortBy, the flows listing things (eg. Playlist) stop as soon as the limit is reached,
+// without loading the next pages.
+func Limit(n int) FlowOption {
+	return func(ic *flowconfig) {
+		ic.limit = n
+	}
+}
+
+// ComposeOptions returns a FlowOption applying all the options, in order.
+func ComposeOptions(opts ...FlowOption) FlowOption {
+	return func(ic *flowconfig) {
+		for _, o := range opts {
+			o(ic)
+		}
+	}
 }
 
 // SingleStringSource sets the source to only emit the param input string.
 func SingleStringSource(input string) FlowOption {
 	return func(ic *flowconfig) {
-		ic.stringSource = func(errors chan<- error) <-chan string {
+		ic.stringSource = func(ctx context.Context, errors chan<- error) <-chan string {
 			output := make(chan string, 1)
 			output <- input
 			close(output)
@@ -29,23 +138,24 @@ func SingleStringSource(input string) FlowOption {
 }
 
 // CSVFirstFieldOnlySource sets a CSV reader as source and emit only the
-// first field/column (or the entire line if the input isn't a proper CSV)
+// first field/column (or the entire line if the input isn't a proper CSV).
+// Reading stops when the flow context is done.
 func CSVFirstFieldOnlySource(input io.Reader) FlowOption {
 	return func(ic *flowconfig) {
-		ic.stringSource = func(errors chan<- error) <-chan string {
+		ic.stringSource = func(ctx context.Context, errors chan<- error) <-chan string {
 			output := make(chan string)
 			go func() {
+				defer close(output)
 				reader := csv.NewReader(input)
-				for {
+				for ctx.Err() == nil {
 					record, err := reader.Read()
 					if err != nil {
 						if err == io.EOF {
-							close(output)
 							return
 						}
 						errors <- fmt.Errorf("csv read error: %w", err)
 					} else {
-						output <- record[0]
+						send(ctx, output, record[0])
 					}
 				}
 			}()
@@ -58,9 +168,12 @@ func CSVFirstFieldOnlySource(input io.Reader) FlowOption {
 // to be written in the CSV output.
 func CSVSink(output io.Writer, columns *[]string) FlowOption {
 	return func(ic *flowconfig) {
-		ic.itemSink = func(errors chan<- error, input <-chan Item) {
+		ic.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
 			w := csv.NewWriter(output)
 			for item := range input {
+				if ctx.Err() != nil {
+					continue
+				}
 				err := w.Write(item.AsRecord(columns))
 				if err != nil {
 					// FIXME fatal?
@@ -79,9 +192,12 @@ func CSVSink(output io.Writer, columns *[]string) FlowOption {
 // The columns param selects the fields of Item to be written in the output.
 func TableSink(output io.Writer, columns *[]string) FlowOption {
 	return func(ic *flowconfig) {
-		ic.itemSink = func(errors chan<- error, input <-chan Item) {
+		ic.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
 			w := tabwriter.NewWriter(output, 0, 8, 2, ' ', 0)
 			for item := range input {
+				if ctx.Err() != nil {
+					continue
+				}
 				_, err := fmt.Fprintln(w, strings.Join(item.AsRecord(columns), "\t"))
 				if err != nil {
 					// FIXME fatal?
@@ -99,7 +215,7 @@ func TableSink(output io.Writer, columns *[]string) FlowOption {
 // NullSink sets a discard sink.
 func NullSink() FlowOption {
 	return func(ic *flowconfig) {
-		ic.itemSink = func(errors chan<- error, input <-chan Item) {
+		ic.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
 			for range input {
 				// noop
 			}
@@ -110,9 +226,12 @@ func NullSink() FlowOption {
 // JSONLinesSink sets a JSON Lines as sink.
 func JSONLinesSink(output io.Writer) FlowOption {
 	return func(ic *flowconfig) {
-		ic.itemSink = func(errors chan<- error, input <-chan Item) {
+		ic.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
 			enc := json.NewEncoder(output)
 			for i := range input {
+				if ctx.Err() != nil {
+					continue
+				}
 				if err := enc.Encode(i); err != nil {
 					errors <- fmt.Errorf("jsonl write error: %w", err)
 				}
@@ -126,5 +245,105 @@ func options2flowconfig(cfgs ...FlowOption) flowconfig {
 	for _, c := range cfgs {
 		c(&cfg)
 	}
+	// filters, sort and limit apply to any sink, whatever the options order
+	if cfg.limit > 0 {
+		cfg.limiter = &limiter{n: cfg.limit}
+	}
+	if sink := cfg.itemSink; sink != nil && (len(cfg.where) > 0 || len(cfg.sortBy) > 0 || cfg.limiter != nil) {
+		where, sortBy, limit := cfg.where, cfg.sortBy, cfg.limiter
+		cfg.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
+			if len(where) > 0 {
+				input = filterItems(ctx, input, where)
+			}
+			if len(sortBy) > 0 {
+				input = sortItems(ctx, input, sortBy)
+			}
+			if limit != nil {
+				// the sink has to write the last items even if the flow is stopped
+				ctx = limit.sinkContext(ctx)
+				input = limitItems(ctx, input, limit.n, limit.reached)
+			}
+			sink(ctx, errors, input)
+		}
+	}
 	return cfg
 }
+
+// limiter stops a flow (see earlyStop) when the Limit is reached.
+type limiter struct {
+	n int
+
+	mu      sync.Mutex
+	parent  context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// earlyStop returns a context canceled as soon as the Limit is reached, to stop loading
+// more things (eg. the next pages of a playlist). Only the flows that just list things
+// use it, and only without SortBy (that needs all the Items).
+// Without Where, every item listed is sent to the sink, so the list calls are limited
+// too (see bigg.WithListLimit): they don't load a page more while the Items drain to the sink.
+func (ic *flowconfig) earlyStop(ctx context.Context) context.Context {
+	l := ic.limiter
+	if l == nil || len(ic.sortBy) > 0 {
+		return ctx
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.parent = ctx
+	ctx, l.cancel = context.WithCancel(ctx)
+	if len(ic.where) == 0 {
+		ctx = bigg.WithListLimit(ctx, l.n)
+	}
+	return ctx
+}
+
+// sinkContext returns the context of the flow before earlyStop.
+func (l *limiter) sinkContext(ctx context.Context) context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.parent != nil {
+		return l.parent
+	}
+	return ctx
+}
+
+func (l *limiter) reached() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		l.cancel()
+		l.stopped = true
+	}
+}
+
+// result ends the flow: it drops the errors caused by earlyStop from the flow errors.
+func (ic *flowconfig) result(err error) error {
+	l := ic.limiter
+	if l == nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		l.cancel()
+	}
+	if err == nil || !l.stopped || l.parent.Err() != nil {
+		return err
+	}
+	errs, ok := err.(MultiErrors)
+	if !ok {
+		errs = MultiErrors{err}
+	}
+	var out MultiErrors
+	for _, e := range errs {
+		if !errors.Is(e, context.Canceled) {
+			out = append(out, e)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
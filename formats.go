@@ -0,0 +1,252 @@
+package youtubetoolkit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Subscriptions export formats of Google Takeout and of alternative YouTube frontends.
+
+// TakeoutSource sets a Google Takeout subscriptions.csv reader as source
+// (a header row, then channel ID, channel URL and channel title) and emits the channel IDs.
+func TakeoutSource(input io.Reader) FlowOption {
+	return func(ic *flowconfig) {
+		ic.stringSource = func(ctx context.Context, errors chan<- error) <-chan string {
+			output := make(chan string)
+			go func() {
+				defer close(output)
+				reader := csv.NewReader(input)
+				reader.FieldsPerRecord = -1
+				for header := true; ctx.Err() == nil; header = false {
+					record, err := reader.Read()
+					if err == io.EOF {
+						return
+					} else if err != nil {
+						errors <- fmt.Errorf("takeout read error: %w", err)
+						return
+					}
+					if header || record[0] == "" {
+						continue
+					}
+					send(ctx, output, record[0])
+				}
+			}()
+			return output
+		}
+	}
+}
+
+// TakeoutSink sets a Google Takeout subscriptions.csv writer as sink.
+func TakeoutSink(output io.Writer) FlowOption {
+	return subsFileSink("takeout", func(subs []*sub) error {
+		w := csv.NewWriter(output)
+		_ = w.Write([]string{"Channel Id", "Channel Url", "Channel Title"})
+		for _, s := range subs {
+			_ = w.Write([]string{s.ChannelId, s.ChannelUrl, s.ChannelTitle})
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+type newPipeSubscriptions struct {
+	AppVersion    string                `json:"app_version"`
+	AppVersionInt int                   `json:"app_version_int"`
+	Subscriptions []newPipeSubscription `json:"subscriptions"`
+}
+
+type newPipeSubscription struct {
+	ServiceId int    `json:"service_id"`
+	Url       string `json:"url"`
+	Name      string `json:"name"`
+}
+
+// NewPipeSource sets a NewPipe subscriptions JSON reader as source and emits the
+// channel IDs of the YouTube subscriptions.
+func NewPipeSource(input io.Reader) FlowOption {
+	return subsFileSource("newpipe", input, func(r io.Reader) ([]string, error) {
+		var doc newPipeSubscriptions
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids := []string{}
+		for _, s := range doc.Subscriptions {
+			if id := urlChannelId(s.Url); s.ServiceId == 0 && id != "" {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	})
+}
+
+// NewPipeSink sets a NewPipe subscriptions JSON writer as sink.
+func NewPipeSink(output io.Writer) FlowOption {
+	return subsFileSink("newpipe", func(subs []*sub) error {
+		doc := newPipeSubscriptions{
+			AppVersion:    "0.25.2",
+			AppVersionInt: 996,
+			Subscriptions: []newPipeSubscription{},
+		}
+		for _, s := range subs {
+			doc.Subscriptions = append(doc.Subscriptions, newPipeSubscription{0, s.ChannelUrl, s.ChannelTitle})
+		}
+		return json.NewEncoder(output).Encode(doc)
+	})
+}
+
+// FreeTube profiles database: a JSON object (a profile) on each line.
+type freeTubeProfile struct {
+	Name          string                 `json:"name"`
+	BgColor       string                 `json:"bgColor"`
+	TextColor     string                 `json:"textColor"`
+	Subscriptions []freeTubeSubscription `json:"subscriptions"`
+	Id            string                 `json:"_id"`
+}
+
+type freeTubeSubscription struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+// FreeTubeSource sets a FreeTube profiles export (.db) reader as source and emits
+// the channel IDs of all the profiles (without duplicates).
+func FreeTubeSource(input io.Reader) FlowOption {
+	return subsFileSource("freetube", input, func(r io.Reader) ([]string, error) {
+		ids := []string{}
+		seen := map[string]bool{}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var p freeTubeProfile
+			if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			for _, s := range p.Subscriptions {
+				if !seen[s.Id] {
+					seen[s.Id] = true
+					ids = append(ids, s.Id)
+				}
+			}
+		}
+		return ids, scanner.Err()
+	})
+}
+
+// FreeTubeSink sets a FreeTube profiles export writer as sink
+// (a single "All Channels" profile).
+func FreeTubeSink(output io.Writer) FlowOption {
+	return subsFileSink("freetube", func(subs []*sub) error {
+		p := freeTubeProfile{
+			Name:          "All Channels",
+			BgColor:       "#000000",
+			TextColor:     "#FFFFFF",
+			Subscriptions: []freeTubeSubscription{},
+			Id:            "allChannels",
+		}
+		for _, s := range subs {
+			p.Subscriptions = append(p.Subscriptions, freeTubeSubscription{s.ChannelId, s.ChannelTitle, s.ChannelThumbUrl})
+		}
+		return json.NewEncoder(output).Encode(p)
+	})
+}
+
+type invidiousExport struct {
+	Subscriptions []string `json:"subscriptions"`
+	WatchHistory  []string `json:"watch_history"`
+	Playlists     []any    `json:"playlists"`
+}
+
+// InvidiousSource sets an Invidious export (JSON) reader as source and emits the
+// channel IDs of the subscriptions.
+func InvidiousSource(input io.Reader) FlowOption {
+	return subsFileSource("invidious", input, func(r io.Reader) ([]string, error) {
+		var doc invidiousExport
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, err
+		}
+		return doc.Subscriptions, nil
+	})
+}
+
+// InvidiousSink sets an Invidious export (JSON) writer as sink.
+func InvidiousSink(output io.Writer) FlowOption {
+	return subsFileSink("invidious", func(subs []*sub) error {
+		doc := invidiousExport{Subscriptions: []string{}, WatchHistory: []string{}, Playlists: []any{}}
+		for _, s := range subs {
+			doc.Subscriptions = append(doc.Subscriptions, s.ChannelId)
+		}
+		return json.NewEncoder(output).Encode(doc)
+	})
+}
+
+// subsFileSource sets as source the channel IDs read by the parse function
+// from a whole subscriptions file.
+func subsFileSource(format string, input io.Reader, parse func(io.Reader) ([]string, error)) FlowOption {
+	return func(ic *flowconfig) {
+		ic.stringSource = func(ctx context.Context, errors chan<- error) <-chan string {
+			output := make(chan string)
+			go func() {
+				defer close(output)
+				ids, err := parse(input)
+				if err != nil {
+					errors <- fmt.Errorf("%s read error: %w", format, err)
+					return
+				}
+				for _, id := range ids {
+					if !send(ctx, output, id) {
+						return
+					}
+				}
+			}()
+			return output
+		}
+	}
+}
+
+// subsFileSink sets as sink a subscriptions file written at once by the write function.
+// Only subscription Items are supported.
+func subsFileSink(format string, write func([]*sub) error) FlowOption {
+	return func(ic *flowconfig) {
+		ic.itemSink = func(ctx context.Context, errors chan<- error, input <-chan Item) {
+			subs := []*sub{}
+			unsupported := false
+			for item := range input {
+				s, ok := item.(*sub)
+				if !ok {
+					if !unsupported {
+						errors <- fmt.Errorf("%s write error: unsupported item %T", format, item)
+						unsupported = true
+					}
+					continue
+				}
+				subs = append(subs, s)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err := write(subs); err != nil {
+				errors <- fmt.Errorf("%s write error: %w", format, err)
+			}
+		}
+	}
+}
+
+// urlChannelId returns the channel ID of a channel URL (empty if it's not one).
+func urlChannelId(u string) string {
+	_, id, ok := strings.Cut(u, "/channel/")
+	if !ok {
+		return ""
+	}
+	id, _, _ = strings.Cut(id, "/")
+	id, _, _ = strings.Cut(id, "?")
+	return id
+}
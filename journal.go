@@ -0,0 +1,193 @@
+package youtubetoolkit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalStatus is the outcome of an input processed by a flow.
+type JournalStatus string
+
+const (
+	JournalDone    JournalStatus = "done"
+	JournalFailed  JournalStatus = "failed"
+	JournalSkipped JournalStatus = "skipped"
+)
+
+// JournalEntry is a line of a journal.
+type JournalEntry struct {
+	Id     string        `json:"id"`
+	Status JournalStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+	// Target is the ID of the resource created for the input (see RecordTarget)
+	Target string    `json:"target,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Journal records the outcome of each input processed by a flow as JSON lines.
+// Resuming from a journal, the inputs already completed (done or skipped)
+// are not processed again, while the failed ones are retried.
+// A nil *Journal is valid and records nothing.
+type Journal struct {
+	mu        sync.Mutex
+	enc       *json.Encoder // nil for a read only journal
+	closer    io.Closer
+	completed map[string]bool   // from the resumed journal
+	targets   map[string]string // from the resumed journal
+}
+
+// NewJournal returns a journal writing to w. If resume is not nil, the entries
+// read from it are used to skip the inputs already completed.
+func NewJournal(w io.Writer, resume io.Reader) (*Journal, error) {
+	j := &Journal{enc: json.NewEncoder(w), completed: map[string]bool{}, targets: map[string]string{}}
+	if resume != nil {
+		scanner := bufio.NewScanner(resume)
+		for line := 1; scanner.Scan(); line++ {
+			var e JournalEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("journal error at line %d: %w", line, err)
+			}
+			// the last outcome of an input wins
+			j.completed[e.Id] = e.Status == JournalDone || e.Status == JournalSkipped
+			if e.Target != "" {
+				j.targets[e.Id] = e.Target
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("journal read error: %w", err)
+		}
+	}
+	return j, nil
+}
+
+// OpenJournal creates a journal file. If resume is true, an existing file
+// is loaded (to skip the inputs already completed) and appended to.
+func OpenJournal(file string, resume bool) (*Journal, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	var r io.Reader
+	if resume {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("journal read error: %w", err)
+		}
+		r = bytes.NewReader(data)
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(file, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("journal open error: %w", err)
+	}
+	j, err := NewJournal(f, r)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	j.closer = f
+	return j, nil
+}
+
+// Close closes the journal file opened by OpenJournal.
+func (j *Journal) Close() error {
+	if j == nil || j.closer == nil {
+		return nil
+	}
+	return j.closer.Close()
+}
+
+// Completed returns true if id was done or skipped in the resumed journal.
+func (j *Journal) Completed(id string) bool {
+	if j == nil {
+		return false
+	}
+	return j.completed[id]
+}
+
+// Target returns the ID of the resource created for id in the resumed journal (see RecordTarget),
+// empty if none.
+func (j *Journal) Target(id string) string {
+	if j == nil {
+		return ""
+	}
+	return j.targets[id]
+}
+
+// Record appends the outcome of an input to the journal.
+func (j *Journal) Record(id string, status JournalStatus, reason string) error {
+	return j.write(JournalEntry{Id: id, Status: status, Reason: reason, Time: time.Now()})
+}
+
+// RecordTarget appends to the journal that the resource target was created for id
+// (eg. the playlist of a clone), so that a resumed flow can continue with it.
+func (j *Journal) RecordTarget(id, target string) error {
+	return j.write(JournalEntry{Id: id, Status: JournalDone, Target: target, Time: time.Now()})
+}
+
+func (j *Journal) write(e JournalEntry) error {
+	if j == nil || j.enc == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(e); err != nil {
+		return fmt.Errorf("journal write error: %w", err)
+	}
+	return nil
+}
+
+// readOnly returns a journal skipping the same completed inputs of j, but recording nothing.
+func (j *Journal) readOnly() *Journal {
+	if j == nil {
+		return nil
+	}
+	return &Journal{completed: j.completed, targets: j.targets}
+}
+
+// flowJournal returns the journal a flow making changes records to. In dry-run (a DryRunService)
+// the journal is read only: the writes are not done, and a run resuming from the journal must not skip them.
+func (tk *Toolkit) flowJournal(j *Journal) *Journal {
+	if _, dryrun := tk.service.(*DryRunService); dryrun {
+		return j.readOnly()
+	}
+	return j
+}
+
+// WithJournal sets a journal for the flows processing a list of inputs
+// (like Subscribe and AddVideoToPlaylist).
+func WithJournal(j *Journal) FlowOption {
+	return func(ic *flowconfig) {
+		ic.journal = j
+	}
+}
+
+// skipCompleted drops the inputs already completed in the journal.
+func (tk *Toolkit) skipCompleted(ctx context.Context, journal *Journal, input <-chan string) <-chan string {
+	if journal == nil {
+		return input
+	}
+	output := make(chan string)
+	go func() {
+		for id := range input {
+			if journal.Completed(id) {
+				tk.log("skipping", id, "(completed in journal)")
+				continue
+			}
+			send(ctx, output, id)
+		}
+		close(output)
+	}()
+	return output
+}
+
+// record records an outcome to the journal, sending to errors any failure.
+func (j *Journal) record(errors chan<- error, id string, status JournalStatus, reason string) {
+	if err := j.Record(id, status, reason); err != nil {
+		errors <- err
+	}
+}
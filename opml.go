@@ -0,0 +1,121 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// OPML_FEED_URL is the RSS feed of the videos of a channel.
+const OPML_FEED_URL = "https://www.youtube.com/feeds/videos.xml?channel_id=%s"
+
+type opml struct {
+	XMLName xml.Name     `xml:"opml"`
+	Version string       `xml:"version,attr"`
+	Title   string       `xml:"head>title"`
+	Body    opmlOutlines `xml:"body>outline"`
+}
+
+type opmlOutlines struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text    string `xml:"text,attr"`
+	Title   string `xml:"title,attr"`
+	Type    string `xml:"type,attr"`
+	XMLUrl  string `xml:"xmlUrl,attr"`
+	HTMLUrl string `xml:"htmlUrl,attr"`
+}
+
+// OPMLSink sets an OPML writer as sink, for RSS readers.
+// Each subscription Item is written as an outline pointing at the channel feed.
+// Other Items are not supported.
+func OPMLSink(output io.Writer) FlowOption {
+	return subsFileSink("opml", func(subs []*sub) error {
+		doc := opml{
+			Version: "1.1",
+			Title:   "YouTube Subscriptions",
+			Body:    opmlOutlines{Text: "YouTube Subscriptions", Title: "YouTube Subscriptions"},
+		}
+		for _, s := range subs {
+			doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+				Text:    s.ChannelTitle,
+				Title:   s.ChannelTitle,
+				Type:    "rss",
+				XMLUrl:  fmt.Sprintf(OPML_FEED_URL, s.ChannelId),
+				HTMLUrl: s.ChannelUrl,
+			})
+		}
+		if _, err := io.WriteString(output, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(output)
+		enc.Indent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		_, err := io.WriteString(output, "\n")
+		return err
+	})
+}
+
+// OPMLSource sets an OPML reader as source and emits the channel IDs of the
+// outlines pointing at a YouTube channel feed (at any nesting level).
+// Other outlines are ignored. Reading stops when the flow context is done.
+func OPMLSource(input io.Reader) FlowOption {
+	return func(ic *flowconfig) {
+		ic.stringSource = func(ctx context.Context, errors chan<- error) <-chan string {
+			output := make(chan string)
+			go func() {
+				defer close(output)
+				dec := xml.NewDecoder(input)
+				for ctx.Err() == nil {
+					tok, err := dec.Token()
+					if err == io.EOF {
+						return
+					} else if err != nil {
+						errors <- fmt.Errorf("opml read error: %w", err)
+						return
+					}
+					el, ok := tok.(xml.StartElement)
+					if !ok || el.Name.Local != "outline" {
+						continue
+					}
+					for _, a := range el.Attr {
+						if a.Name.Local != "xmlUrl" {
+							continue
+						}
+						if id := feedChannelId(a.Value); id != "" {
+							send(ctx, output, id)
+						}
+					}
+				}
+			}()
+			return output
+		}
+	}
+}
+
+// feedChannelId returns the channel ID of a YouTube channel feed URL (empty if it's not one,
+// like the feeds of other sites with a channel_id parameter).
+func feedChannelId(feed string) string {
+	u, err := url.Parse(feed)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "youtube.com", "www.youtube.com":
+	default:
+		return ""
+	}
+	if u.Path != "/feeds/videos.xml" {
+		return ""
+	}
+	return u.Query().Get("channel_id")
+}
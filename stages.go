@@ -1,24 +1,42 @@
 package youtubetoolkit
 
 import (
+	"context"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/raffaelecassia/youtubetoolkit/bigg"
 )
 
-func (tk *Toolkit) channels2newsubscriptions(errors chan<- error, channelIds <-chan string) <-chan *bigg.Sub {
+// Stages keep draining their input after ctx is done (without doing any work),
+// so that every upstream goroutine can always complete its sends and exit.
+
+func (tk *Toolkit) channels2newsubscriptions(ctx context.Context, errors chan<- error, channelIds <-chan string, journal *Journal) <-chan *bigg.Sub {
 	subs := make(chan *bigg.Sub)
+	budget := tk.newBudgetGate(errors)
 	go func() {
 		for channelId := range channelIds {
+			if ctx.Err() != nil {
+				continue
+			}
+			if !budget.allows(bigg.QUOTA_COST_INSERT) {
+				journal.record(errors, channelId, JournalFailed, ErrQuotaBudgetExceeded.Error())
+				continue
+			}
 			tk.logf("subscribing to %s... ", channelId)
-			sub, err := tk.service.SubscriptionInsert(channelId)
+			sub, err := tk.service.SubscriptionInsert(ctx, channelId)
 			if err != nil {
 				errors <- fmt.Errorf("channel %s subscribe: %w", channelId, err)
+				journal.record(errors, channelId, JournalFailed, err.Error())
 				tk.log("fail!")
 			} else {
-				subs <- sub
+				journal.record(errors, channelId, JournalDone, "")
+				send(ctx, subs, sub)
 				tk.log("channel", sub.Snippet.Title, "added")
 			}
 		}
@@ -27,22 +45,62 @@ func (tk *Toolkit) channels2newsubscriptions(errors chan<- error, channelIds <-c
 	return subs
 }
 
-func (tk *Toolkit) channels2channelvideouploads(errors chan<- error, channelIds <-chan string, filter func(*bigg.PlaylistItem) (bool, error), numDigesters int) <-chan *bigg.PlaylistItem {
-	output := make(chan *bigg.PlaylistItem, 10)
+// channels2channelvideouploads sends the uploads of the channels accepted by filter,
+// listing the given variants of their uploads playlist (see uploadsVariant).
+// The uploads playlist ID of the standard channel IDs is derived locally (see
+// uploadsPlaylistId), the channel is looked up only if that playlist is not found.
+func (tk *Toolkit) channels2channelvideouploads(ctx context.Context, errors chan<- error, channelIds <-chan string, variants []uploadsVariant, filter func(*bigg.PlaylistItem) (bool, error), numDigesters int) <-chan Item {
+	output := make(chan Item, 10)
+	uploads := tk.channels2uploadsplaylists(ctx, errors, channelIds)
 	var wg sync.WaitGroup
+	var saved int32
+	budget := tk.newBudgetGate(errors)
 	wg.Add(numDigesters)
 	for i := 0; i < numDigesters; i++ {
 		go func() {
-			for id := range channelIds {
-				tk.log("Checking channel", id)
-				c, err := tk.service.GetChannelInfo(id)
-				if err != nil {
-					errors <- err
-				} else {
-					plid := c.ContentDetails.RelatedPlaylists.Uploads
-					err := tk.service.PlaylistItemsList(plid, filter, output)
+			for u := range uploads {
+				if ctx.Err() != nil {
+					continue
+				}
+				tk.log("Checking channel", u.channelId)
+				for _, v := range variants {
+					if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_LIST) {
+						break
+					}
+					plid, ok := v.playlistId(u.playlistId)
+					if !ok {
+						errors <- fmt.Errorf("channel %s: no %s uploads playlist for %s", u.channelId, v.kind, u.playlistId)
+						continue
+					}
+					err := tk.uploads(ctx, plid, v.kind, filter, output)
+					if err == nil {
+						if u.derived && v == allUploads {
+							atomic.AddInt32(&saved, 1)
+						}
+						continue
+					} else if !bigg.IsNotFound(err) {
+						errors <- err
+						continue
+					} else if v != allUploads {
+						// variants are not found when the channel has no uploads of that kind
+						tk.log("Playlist", plid, "not found, no", v.kind, "uploads")
+						continue
+					} else if !u.derived {
+						errors <- err
+						continue
+					}
+					if !budget.allows(2 * bigg.QUOTA_COST_LIST) {
+						break
+					}
+					tk.log("Uploads playlist", plid, "not found, looking up channel", u.channelId)
+					c, err := tk.service.GetChannelInfo(ctx, u.channelId)
 					if err != nil {
 						errors <- err
+					} else {
+						plid := c.ContentDetails.RelatedPlaylists.Uploads
+						if err := tk.uploads(ctx, plid, v.kind, filter, output); err != nil {
+							errors <- err
+						}
 					}
 				}
 			}
@@ -51,21 +109,250 @@ func (tk *Toolkit) channels2channelvideouploads(errors chan<- error, channelIds
 	}
 	go func() {
 		wg.Wait()
+		if n := atomic.LoadInt32(&saved); n > 0 {
+			tk.log("Uploads playlists derived from channel IDs:", n, "channel lookups saved")
+		}
+		close(output)
+	}()
+	return output
+}
+
+// uploads sends the items of an uploads playlist accepted by filter,
+// as playlistItem Items of the given kind.
+func (tk *Toolkit) uploads(ctx context.Context, playlistId string, kind VideoKind, filter func(*bigg.PlaylistItem) (bool, error), output chan<- Item) error {
+	items := make(chan *bigg.PlaylistItem)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tk.service.PlaylistItemsList(ctx, playlistId, filter, items)
+		close(items)
+	}()
+	for i := range items {
+		r := newPlaylistItemRecord(i, "")
+		r.kind = kind
+		send[Item](ctx, output, r)
+	}
+	return <-errc
+}
+
+// uploadsPlaylist is the uploads playlist of a channel.
+type uploadsPlaylist struct {
+	channelId  string
+	playlistId string
+	derived    bool // from the channel ID, not looked up
+}
+
+// channels2uploadsplaylists sends the uploads playlists of the channels. The ones of the
+// standard channel IDs are derived locally, the others are looked up in batches.
+func (tk *Toolkit) channels2uploadsplaylists(ctx context.Context, errors chan<- error, channelIds <-chan string) <-chan uploadsPlaylist {
+	output := make(chan uploadsPlaylist, 10)
+	lookups := make(chan string)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		for id := range channelIds {
+			if plid, ok := uploadsPlaylistId(id); ok {
+				send(ctx, output, uploadsPlaylist{id, plid, true})
+			} else {
+				send(ctx, lookups, id)
+			}
+		}
+		close(lookups)
+		wg.Done()
+	}()
+	channels := batchLookup(ctx, errors, lookups, bigg.MAX_BATCH_IDS, batchFlushTimeout,
+		tk.newBudgetGate(errors), "channel", tk.service.ChannelsList,
+		func(c *bigg.Channel) string { return c.Id })
+	go func() {
+		for c := range channels {
+			send(ctx, output, uploadsPlaylist{c.Id, c.ContentDetails.RelatedPlaylists.Uploads, false})
+		}
+		wg.Done()
+	}()
+	go func() {
+		wg.Wait()
+		close(output)
+	}()
+	return output
+}
+
+// uploadsPlaylistId returns the uploads playlist ID of a standard channel ID
+// ("UC" followed by 22 chars): the same ID with the "UU" prefix.
+func uploadsPlaylistId(channelId string) (string, bool) {
+	if len(channelId) != 24 || !strings.HasPrefix(channelId, "UC") {
+		return "", false
+	}
+	return "UU" + channelId[2:], true
+}
+
+// batchFlushTimeout is how long batches waits for more inputs before sending an incomplete batch.
+const batchFlushTimeout = 200 * time.Millisecond
+
+// batches groups the inputs in batches of up to size inputs, in input order.
+// An incomplete batch is sent when no more inputs arrive within flush.
+func batches[T any](ctx context.Context, input <-chan T, size int, flush time.Duration) <-chan []T {
+	output := make(chan []T)
+	go func() {
+		batch := []T{}
+		sendBatch := func() {
+			if len(batch) > 0 {
+				send(ctx, output, batch)
+				batch = []T{}
+			}
+		}
+		timer := time.NewTimer(flush)
+		stopTimer := func() {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		stopTimer()
+	loop:
+		for {
+			select {
+			case v, ok := <-input:
+				if !ok {
+					break loop
+				}
+				if len(batch) == 0 {
+					timer.Reset(flush)
+				}
+				batch = append(batch, v)
+				if len(batch) == size {
+					stopTimer()
+					sendBatch()
+				}
+			case <-timer.C:
+				sendBatch()
+			}
+		}
+		stopTimer()
+		sendBatch()
+		close(output)
+	}()
+	return output
+}
+
+// batchLookup groups the input IDs in batches of up to size IDs (see batches) and looks up
+// each batch with a single call (like bigg.Youtube.ChannelsList, 1 unit for up to 50 IDs).
+// Results are sent in input order; the IDs missing from a result are reported as errors.
+func batchLookup[T any](ctx context.Context, errors chan<- error, ids <-chan string, size int, flush time.Duration, budget *budgetGate, kind string, lookup func(context.Context, []string) ([]T, error), idOf func(T) string) <-chan T {
+	output := make(chan T, size)
+	go func() {
+		for batch := range batches(ctx, ids, size, flush) {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_LIST) {
+				continue
+			}
+			found, err := lookupUnique(ctx, batch, lookup, idOf)
+			if err != nil {
+				errors <- err
+				continue
+			}
+			for _, id := range batch {
+				r, ok := found[id]
+				if !ok {
+					errors <- fmt.Errorf("%s not found for id=%s", kind, id)
+					continue
+				}
+				send(ctx, output, r)
+			}
+		}
+		close(output)
+	}()
+	return output
+}
+
+// lookupUnique looks up the IDs (without duplicates) with a single call
+// and returns the results by ID.
+func lookupUnique[T any](ctx context.Context, ids []string, lookup func(context.Context, []string) ([]T, error), idOf func(T) string) (map[string]T, error) {
+	unique := []string{}
+	found := map[string]T{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	res, err := lookup(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res {
+		found[idOf(r)] = r
+	}
+	return found, nil
+}
+
+// videoDetails joins the video details (duration, statistics, live status and definition)
+// onto the playlistItem Items, looking up the videos in batches of 50 (1 unit each).
+// Items are sent in input order. Other Items, and the videos not found (eg. private or
+// deleted), pass through as they are.
+func (tk *Toolkit) videoDetails(ctx context.Context, errors chan<- error, input <-chan Item) <-chan Item {
+	output := make(chan Item, 10)
+	budget := tk.newBudgetGate(errors)
+	go func() {
+		for batch := range batches(ctx, input, bigg.MAX_BATCH_IDS, batchFlushTimeout) {
+			if ctx.Err() != nil {
+				continue
+			}
+			ids := []string{}
+			for _, i := range batch {
+				if pi, ok := i.(*playlistItem); ok {
+					ids = append(ids, pi.VideoId)
+				}
+			}
+			if len(ids) > 0 && budget.allows(bigg.QUOTA_COST_LIST) {
+				found, err := lookupUnique(ctx, ids, tk.service.VideosList, func(v *bigg.Video) string { return v.Id })
+				if err != nil {
+					errors <- err
+				}
+				for _, i := range batch {
+					if pi, ok := i.(*playlistItem); ok {
+						if v, ok := found[pi.VideoId]; ok {
+							pi.setVideoDetails(v)
+						} else if err == nil {
+							tk.log("video details not found for id", pi.VideoId)
+						}
+					}
+				}
+			}
+			for _, i := range batch {
+				send(ctx, output, i)
+			}
+		}
 		close(output)
 	}()
 	return output
 }
 
-func (tk *Toolkit) videos2playlist(errors chan<- error, playlistId string, videoIds <-chan string) <-chan *bigg.PlaylistItem {
+// videos2playlist inserts the videos in a playlist, at consecutive positions from position
+// (to keep the input order) or appended with bigg.POSITION_APPEND.
+func (tk *Toolkit) videos2playlist(ctx context.Context, errors chan<- error, playlistId string, videoIds <-chan string, position int64, journal *Journal) <-chan *bigg.PlaylistItem {
 	output := make(chan *bigg.PlaylistItem)
+	budget := tk.newBudgetGate(errors)
 	go func() {
 		for id := range videoIds {
+			if ctx.Err() != nil {
+				continue
+			}
+			if !budget.allows(bigg.QUOTA_COST_INSERT) {
+				journal.record(errors, id, JournalFailed, ErrQuotaBudgetExceeded.Error())
+				continue
+			}
 			tk.log("Adding video", id)
-			pli, err := tk.service.PlaylistItemsInsert(playlistId, id)
+			pli, err := tk.service.PlaylistItemsInsert(ctx, playlistId, id, position)
 			if err != nil {
 				errors <- err
+				journal.record(errors, id, JournalFailed, err.Error())
 			} else {
-				output <- pli
+				if position != bigg.POSITION_APPEND {
+					position++
+				}
+				journal.record(errors, id, JournalDone, "")
+				send(ctx, output, pli)
 			}
 		}
 		close(output)
@@ -73,74 +360,431 @@ func (tk *Toolkit) videos2playlist(errors chan<- error, playlistId string, video
 	return output
 }
 
-func sortPlaylistItemByPublishedAt(input <-chan *bigg.PlaylistItem) <-chan *bigg.PlaylistItem {
-	output := make(chan *bigg.PlaylistItem, 10)
+// cloneableVideos returns the videos of the playlist items in order, without the
+// deleted and private videos (they have no owner channel) and the duplicates.
+func (tk *Toolkit) cloneableVideos(items []*bigg.PlaylistItem) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, i := range items {
+		id := i.Snippet.ResourceId.VideoId
+		if i.Snippet.VideoOwnerChannelId == "" {
+			tk.log("skipping unavailable video", id, "("+i.Snippet.Title+")")
+			continue
+		} else if seen[id] {
+			tk.log("skipping duplicate video", id)
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// videoIdLength is the length of the video IDs, shorter than the playlist item IDs.
+const videoIdLength = 11
+
+// removeFromPlaylist deletes the playlist items of the input IDs (videos or playlist items),
+// loading the playlist items with the first input to resolve them.
+// IDs not in the playlist are skipped.
+func (tk *Toolkit) removeFromPlaylist(ctx context.Context, errors chan<- error, playlistId string, ids <-chan string, journal *Journal) <-chan *bigg.PlaylistItem {
+	output := make(chan *bigg.PlaylistItem)
+	budget := tk.newBudgetGate(errors)
 	go func() {
-		items := []*bigg.PlaylistItem{}
+		defer close(output)
+		var byVideo map[string][]*bigg.PlaylistItem
+		var byId map[string]*bigg.PlaylistItem
+		for id := range ids {
+			if ctx.Err() != nil {
+				continue
+			}
+			if byId == nil {
+				tk.log("Loading playlist", playlistId, "...")
+				current, err := tk.playlistItems(ctx, playlistId)
+				if err != nil {
+					errors <- fmt.Errorf("playlist preload: %w", err)
+					for range ids {
+						// drains
+					}
+					return
+				}
+				byVideo = map[string][]*bigg.PlaylistItem{}
+				byId = map[string]*bigg.PlaylistItem{}
+				for _, i := range current {
+					byVideo[i.Snippet.ResourceId.VideoId] = append(byVideo[i.Snippet.ResourceId.VideoId], i)
+					byId[i.Id] = i
+				}
+			}
+			var items []*bigg.PlaylistItem
+			if len(id) == videoIdLength {
+				items = byVideo[id]
+				delete(byVideo, id)
+			} else if i, ok := byId[id]; ok {
+				items = []*bigg.PlaylistItem{i}
+			}
+			if len(items) == 0 {
+				tk.log(id, "not in playlist")
+				journal.record(errors, id, JournalSkipped, "not in playlist")
+				continue
+			}
+			var failed error
+			for _, i := range items {
+				if byId[i.Id] == nil {
+					continue // already deleted by its item ID
+				}
+				if !budget.allows(bigg.QUOTA_COST_DELETE) {
+					failed = ErrQuotaBudgetExceeded
+					break
+				}
+				tk.log("Removing video", i.Snippet.ResourceId.VideoId)
+				if err := tk.service.PlaylistItemsDelete(ctx, i.Id); err != nil {
+					errors <- err
+					failed = err
+					break
+				}
+				delete(byId, i.Id)
+				send(ctx, output, i)
+			}
+			if failed != nil {
+				journal.record(errors, id, JournalFailed, failed.Error())
+			} else {
+				journal.record(errors, id, JournalDone, "")
+			}
+		}
+	}()
+	return output
+}
+
+// sortPlaylistItemByPublishedAt buffers all the playlistItem Items and sends them
+// sorted by the published date (oldest first).
+func sortPlaylistItemByPublishedAt(ctx context.Context, input <-chan Item) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		items := []*playlistItem{}
 		// buffers all items
 		for i := range input {
-			items = append(items, i)
+			if pi, ok := i.(*playlistItem); ok {
+				items = append(items, pi)
+			}
 		}
 		// sorts
 		sort.Slice(items, func(i, j int) bool {
-			return items[i].Snippet.PublishedAt < items[j].Snippet.PublishedAt
+			return items[i].PublishedAt < items[j].PublishedAt
 		})
 		// sends items to chan
 		for _, pi := range items {
-			output <- pi
+			if !send[Item](ctx, output, pi) {
+				break
+			}
 		}
 		close(output)
 	}()
 	return output
 }
 
-func sub2item(input <-chan *bigg.Sub) <-chan Item {
+// skipSubscribed loads the user subscriptions and splits the input channels in the
+// ones to subscribe to and the ones already subscribed. Duplicates in input are dropped.
+func (tk *Toolkit) skipSubscribed(ctx context.Context, errors chan<- error, channelIds <-chan string, journal *Journal) (<-chan string, <-chan *bigg.Sub) {
+	output := make(chan string)
+	skipped := make(chan *bigg.Sub)
+	go func() {
+		defer close(output)
+		defer close(skipped)
+		tk.log("Loading subscriptions...")
+		subscribed, err := tk.subscribedChannels(ctx)
+		if err != nil {
+			errors <- fmt.Errorf("subscriptions preload: %w", err)
+			for range channelIds {
+				// drains
+			}
+			return
+		}
+		seen := map[string]bool{}
+		for id := range channelIds {
+			if seen[id] {
+				tk.log("duplicate channel", id)
+				journal.record(errors, id, JournalSkipped, "duplicate")
+				continue
+			}
+			seen[id] = true
+			if s, ok := subscribed[id]; ok {
+				tk.log("already subscribed to", id)
+				journal.record(errors, id, JournalSkipped, "already subscribed")
+				send(ctx, skipped, s)
+				continue
+			}
+			send(ctx, output, id)
+		}
+	}()
+	return output, skipped
+}
+
+// subscribedChannels returns the user subscriptions by channel id.
+func (tk *Toolkit) subscribedChannels(ctx context.Context) (map[string]*bigg.Sub, error) {
+	subs, err := tk.subscriptions(ctx)
+	out := map[string]*bigg.Sub{}
+	for _, s := range subs {
+		out[s.Snippet.ResourceId.ChannelId] = s
+	}
+	return out, err
+}
+
+// subscriptions returns the user subscriptions, in the order of the API.
+func (tk *Toolkit) subscriptions(ctx context.Context) ([]*bigg.Sub, error) {
+	subs := make(chan *bigg.Sub)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tk.service.SubscriptionsList(ctx, subs)
+		close(subs)
+	}()
+	out := []*bigg.Sub{}
+	for s := range subs {
+		out = append(out, s)
+	}
+	return out, <-errc
+}
+
+func sub2item(ctx context.Context, input <-chan *bigg.Sub, status string) <-chan Item {
 	output := make(chan Item, 10)
 	go func() {
 		for s := range input {
-			output <- &sub{
-				SubscriptionId:  s.Id,
-				ChannelId:       s.Snippet.ResourceId.ChannelId,
-				ChannelTitle:    s.Snippet.Title,
-				ChannelUrl:      fmt.Sprintf("https://www.youtube.com/channel/%s", s.Snippet.ResourceId.ChannelId),
-				ChannelThumbUrl: s.Snippet.Thumbnails.Default.Url,
-			}
+			send[Item](ctx, output, newSubRecord(s, status))
 		}
 		close(output)
 	}()
 	return output
 }
 
-func playlist2item(input <-chan *bigg.Playlist) <-chan Item {
+func newSubRecord(s *bigg.Sub, status string) *sub {
+	return &sub{
+		SubscriptionId:  s.Id,
+		ChannelId:       s.Snippet.ResourceId.ChannelId,
+		ChannelTitle:    s.Snippet.Title,
+		ChannelUrl:      fmt.Sprintf("https://www.youtube.com/channel/%s", s.Snippet.ResourceId.ChannelId),
+		ChannelThumbUrl: s.Snippet.Thumbnails.Default.Url,
+		Status:          status,
+	}
+}
+
+func playlist2item(ctx context.Context, input <-chan *bigg.Playlist) <-chan Item {
 	output := make(chan Item, 10)
 	go func() {
 		for i := range input {
-			output <- &playlist{
+			pl := &playlist{
 				PlaylistId:    i.Id,
 				PlaylistTitle: i.Snippet.Title,
+				Description:   i.Snippet.Description,
+				PublishedAt:   i.Snippet.PublishedAt,
 				VideoCount:    i.ContentDetails.ItemCount,
 			}
+			if i.Status != nil {
+				pl.PrivacyStatus = i.Status.PrivacyStatus
+			}
+			send[Item](ctx, output, pl)
 		}
 		close(output)
 	}()
 	return output
 }
 
-func playlistItem2item(input <-chan *bigg.PlaylistItem) <-chan Item {
+// skipInPlaylist loads the items of a playlist and splits the input videos in the
+// ones to add and the ones already in the playlist. Duplicates in input are dropped.
+func (tk *Toolkit) skipInPlaylist(ctx context.Context, errors chan<- error, playlistId string, videoIds <-chan string, journal *Journal) (<-chan string, <-chan *bigg.PlaylistItem) {
+	output := make(chan string)
+	skipped := make(chan *bigg.PlaylistItem)
+	go func() {
+		defer close(output)
+		defer close(skipped)
+		tk.log("Loading playlist", playlistId, "...")
+		existing, err := tk.playlistVideos(ctx, playlistId)
+		if err != nil {
+			errors <- fmt.Errorf("playlist preload: %w", err)
+			for range videoIds {
+				// drains
+			}
+			return
+		}
+		seen := map[string]bool{}
+		for id := range videoIds {
+			if seen[id] {
+				tk.log("duplicate video", id)
+				journal.record(errors, id, JournalSkipped, "duplicate")
+				continue
+			}
+			seen[id] = true
+			if pli, ok := existing[id]; ok {
+				tk.log("video", id, "already in playlist")
+				journal.record(errors, id, JournalSkipped, "already in playlist")
+				send(ctx, skipped, pli)
+				continue
+			}
+			send(ctx, output, id)
+		}
+	}()
+	return output, skipped
+}
+
+// playlistVideos returns the items of a playlist by video id.
+func (tk *Toolkit) playlistVideos(ctx context.Context, playlistId string) (map[string]*bigg.PlaylistItem, error) {
+	items, err := tk.playlistItems(ctx, playlistId)
+	out := map[string]*bigg.PlaylistItem{}
+	for _, i := range items {
+		out[i.Snippet.ResourceId.VideoId] = i
+	}
+	return out, err
+}
+
+// playlistItems returns the items of a playlist, in playlist order.
+func (tk *Toolkit) playlistItems(ctx context.Context, playlistId string) ([]*bigg.PlaylistItem, error) {
+	items := make(chan *bigg.PlaylistItem)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tk.service.PlaylistItemsList(ctx, playlistId, allPlaylistItems(), items)
+		close(items)
+	}()
+	out := []*bigg.PlaylistItem{}
+	for i := range items {
+		out = append(out, i)
+	}
+	return out, <-errc
+}
+
+func playlistItem2item(ctx context.Context, input <-chan *bigg.PlaylistItem, status string) <-chan Item {
 	output := make(chan Item, 10)
 	go func() {
 		for i := range input {
-			output <- &playlistItem{
-				PlaylistItemId: i.Id,
-				ChannelId:      i.Snippet.VideoOwnerChannelId,
-				ChannelTitle:   i.Snippet.VideoOwnerChannelTitle,
-				ChannelUrl:     fmt.Sprintf("https://www.youtube.com/channel/%s", i.Snippet.VideoOwnerChannelId),
-				VideoId:        i.Snippet.ResourceId.VideoId,
-				VideoTitle:     i.Snippet.Title,
-				VideoUrl:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", i.Snippet.ResourceId.VideoId),
-				PublishedAt:    i.Snippet.PublishedAt,
+			send[Item](ctx, output, newPlaylistItemRecord(i, status))
+		}
+		close(output)
+	}()
+	return output
+}
+
+func newPlaylistItemRecord(i *bigg.PlaylistItem, status string) *playlistItem {
+	r := &playlistItem{
+		PlaylistItemId: i.Id,
+		ChannelId:      i.Snippet.VideoOwnerChannelId,
+		ChannelTitle:   i.Snippet.VideoOwnerChannelTitle,
+		ChannelUrl:     fmt.Sprintf("https://www.youtube.com/channel/%s", i.Snippet.VideoOwnerChannelId),
+		VideoId:        i.Snippet.ResourceId.VideoId,
+		VideoTitle:     i.Snippet.Title,
+		VideoUrl:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", i.Snippet.ResourceId.VideoId),
+		PublishedAt:    i.Snippet.PublishedAt,
+		Status:         status,
+		Position:       i.Snippet.Position,
+	}
+	if i.ContentDetails != nil {
+		r.VideoPublishedAt = i.ContentDetails.VideoPublishedAt
+	}
+	return r
+}
+
+// filterItems sends only the Items matching all the filters.
+func filterItems(ctx context.Context, input <-chan Item, filters []func(Item) bool) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+	items:
+		for i := range input {
+			for _, match := range filters {
+				if !match(i) {
+					continue items
+				}
 			}
+			send(ctx, output, i)
+		}
+		close(output)
+	}()
+	return output
+}
+
+// sortItems buffers all the Items and sends them sorted by the keys (a stable sort).
+// Numeric fields are compared as numbers, the others as strings.
+func sortItems(ctx context.Context, input <-chan Item, keys []SortKey) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		items := []Item{}
+		for i := range input {
+			items = append(items, i)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return lessByKeys(items[i], items[j], keys)
+		})
+		for _, i := range items {
+			if !send(ctx, output, i) {
+				break
+			}
+		}
+		close(output)
+	}()
+	return output
+}
+
+// lessByKeys returns true if the Item a sorts before b by the keys.
+func lessByKeys(a, b Item, keys []SortKey) bool {
+	for _, k := range keys {
+		c := compareField(a, b, k.Field)
+		if c != 0 {
+			return (c < 0) != k.Desc
 		}
+	}
+	return false
+}
+
+// sortFields are the fields sorted by another one (Duration is ISO 8601, eg. "PT1M30S").
+var sortFields = map[string]string{"Duration": "DurationSeconds"}
+
+// compareField compares the field of two Items: as numbers if it's numeric in both, as strings otherwise.
+func compareField(a, b Item, field string) int {
+	if f, ok := sortFields[field]; ok {
+		field = f
+	}
+	va, _ := fieldValue(a, field)
+	vb, _ := fieldValue(b, field)
+	if isNumericField(a, field) && isNumericField(b, field) {
+		na, errA := strconv.ParseFloat(va, 64)
+		nb, errB := strconv.ParseFloat(vb, 64)
+		if errA == nil && errB == nil {
+			return compareFloats(na, nb)
+		}
+	}
+	return strings.Compare(va, vb)
+}
+
+// limitItems sends only the first n Items, calling reached once they are sent.
+func limitItems(ctx context.Context, input <-chan Item, n int, reached func()) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		sent := 0
+		for i := range input {
+			if sent >= n {
+				continue
+			}
+			if send(ctx, output, i) {
+				sent++
+			}
+			if sent == n {
+				reached()
+			}
+		}
+		close(output)
+	}()
+	return output
+}
+
+// mergeItems merges the items of all inputs into a single channel.
+func mergeItems(ctx context.Context, inputs ...<-chan Item) <-chan Item {
+	output := make(chan Item, 10)
+	var wg sync.WaitGroup
+	wg.Add(len(inputs))
+	for _, input := range inputs {
+		go func(input <-chan Item) {
+			for i := range input {
+				send(ctx, output, i)
+			}
+			wg.Done()
+		}(input)
+	}
+	go func() {
+		wg.Wait()
 		close(output)
 	}()
 	return output
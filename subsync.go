@@ -0,0 +1,145 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+)
+
+// subscriptionsDiff is the comparison of the subscriptions of two accounts, A and B.
+type subscriptionsDiff struct {
+	onlyA  []*bigg.Sub
+	common []*bigg.Sub // A's subscriptions
+	onlyB  []*bigg.Sub
+}
+
+// diffSubscriptions loads (in parallel) and compares the subscriptions of two accounts.
+func diffSubscriptions(ctx context.Context, a, b *Toolkit) (*subscriptionsDiff, error) {
+	var subsB []*bigg.Sub
+	errB := make(chan error, 1)
+	go func() {
+		var err error
+		subsB, err = b.subscriptions(ctx)
+		errB <- err
+	}()
+	subsA, err := a.subscriptions(ctx)
+	if err := <-errB; err != nil {
+		return nil, fmt.Errorf("subscriptions of B: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("subscriptions of A: %w", err)
+	}
+
+	inA := map[string]bool{}
+	for _, s := range subsA {
+		inA[s.Snippet.ResourceId.ChannelId] = true
+	}
+	inB := map[string]bool{}
+	for _, s := range subsB {
+		inB[s.Snippet.ResourceId.ChannelId] = true
+	}
+	diff := &subscriptionsDiff{}
+	for _, s := range subsA {
+		if inB[s.Snippet.ResourceId.ChannelId] {
+			diff.common = append(diff.common, s)
+		} else {
+			diff.onlyA = append(diff.onlyA, s)
+		}
+	}
+	for _, s := range subsB {
+		if !inA[s.Snippet.ResourceId.ChannelId] {
+			diff.onlyB = append(diff.onlyB, s)
+		}
+	}
+	return diff, nil
+}
+
+// subscriptionsDiff compares the subscriptions of tk (A) with other's (B) and
+// sends them with status only-a, only-b or common.
+func (tk *Toolkit) subscriptionsDiff(ctx context.Context, errors chan<- error, other *Toolkit) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		defer close(output)
+		tk.log("Loading subscriptions...")
+		diff, err := diffSubscriptions(ctx, tk, other)
+		if err != nil {
+			errors <- err
+			return
+		}
+		tk.logf("%d only in A, %d only in B, %d in common\n", len(diff.onlyA), len(diff.onlyB), len(diff.common))
+		for _, d := range []struct {
+			subs   []*bigg.Sub
+			status string
+		}{{diff.onlyA, statusOnlyA}, {diff.onlyB, statusOnlyB}, {diff.common, statusCommon}} {
+			for _, s := range d.subs {
+				if !send[Item](ctx, output, newSubRecord(s, d.status)) {
+					return
+				}
+			}
+		}
+	}()
+	return output
+}
+
+// planSubscriptionsSync compares the subscriptions of from (A) with tk's (B) and emits
+// the channels B has to subscribe to and, with prune, the subscriptions B has to remove.
+// The plan cost is checked against the quota budget before emitting anything.
+func (tk *Toolkit) planSubscriptionsSync(ctx context.Context, errors chan<- error, from *Toolkit, prune bool) (<-chan string, <-chan *bigg.Sub) {
+	add := make(chan string)
+	del := make(chan *bigg.Sub)
+	go func() {
+		defer close(add)
+		defer close(del)
+		tk.log("Loading subscriptions...")
+		diff, err := diffSubscriptions(ctx, from, tk)
+		if err != nil {
+			errors <- err
+			return
+		}
+		if !prune {
+			diff.onlyB = nil
+		}
+		cost := uint32(len(diff.onlyA))*bigg.QUOTA_COST_INSERT + uint32(len(diff.onlyB))*bigg.QUOTA_COST_DELETE
+		tk.logf("Sync plan: %d subscriptions, %d unsubscriptions (quota cost: %d units)\n", len(diff.onlyA), len(diff.onlyB), cost)
+		if err := tk.checkQuota(cost); err != nil {
+			errors <- err
+			return
+		}
+		for _, s := range diff.onlyA {
+			if !send(ctx, add, s.Snippet.ResourceId.ChannelId) {
+				return
+			}
+		}
+		for _, s := range diff.onlyB {
+			if !send(ctx, del, s) {
+				return
+			}
+		}
+	}()
+	return add, del
+}
+
+func (tk *Toolkit) subscriptions2unsubscriptions(ctx context.Context, errors chan<- error, subs <-chan *bigg.Sub) <-chan *bigg.Sub {
+	output := make(chan *bigg.Sub)
+	budget := tk.newBudgetGate(errors)
+	go func() {
+		for s := range subs {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_DELETE) {
+				continue
+			}
+			// the subscription ID is known, no need to look it up by channel ID
+			channelId := s.Snippet.ResourceId.ChannelId
+			tk.logf("unsubscribing from %s... ", channelId)
+			if err := tk.service.SubscriptionDeleteById(ctx, s.Id); err != nil {
+				errors <- fmt.Errorf("channel %s unsubscribe: %w", channelId, err)
+				tk.log("fail!")
+			} else {
+				send(ctx, output, s)
+				tk.log("done")
+			}
+		}
+		close(output)
+	}()
+	return output
+}
@@ -1,6 +1,8 @@
 package youtubetoolkit
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -53,6 +55,27 @@ func (e MultiErrors) Error() string {
 	return msg
 }
 
+// Is reports whether any of the errors matches target (see errors.Is).
+func (e MultiErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends v to ch unless ctx is done first.
+// Returns false if v was not sent.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // multiErrorsHandler handles multiple errors.
 // It returns two channels: a send-only for errors and a receive-only to get a MultiErrors.
 // Errors received from the first channel are internally stored until the channel is closed.
@@ -0,0 +1,300 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+)
+
+// syncPlan is the list of changes that makes a playlist match a list of videos.
+// Changes are applied in order: deletes, moves, inserts (each at its final position).
+type syncPlan struct {
+	deletes []*bigg.PlaylistItem
+	moves   []syncMove
+	inserts []syncMove
+}
+
+// syncMove moves (or inserts) a video, or a playlist item (see sortPlaylist), to a zero based position of the playlist.
+type syncMove struct {
+	id       string
+	position int64
+}
+
+// cost returns the quota cost of the plan.
+func (p *syncPlan) cost() uint32 {
+	return uint32(len(p.deletes))*bigg.QUOTA_COST_DELETE +
+		uint32(len(p.inserts))*bigg.QUOTA_COST_INSERT +
+		uint32(len(p.moves))*bigg.QUOTA_COST_UPDATE
+}
+
+// planSync returns the changes that make the current playlist items match
+// the desired videos (without duplicates). Videos not desired and duplicated
+// items are deleted, the videos kept are reordered, then missing videos are inserted
+// in desired order, each at its desired position: an insert never needs a move.
+func planSync(current []*bigg.PlaylistItem, desired []string) *syncPlan {
+	plan := &syncPlan{}
+	wanted := map[string]bool{}
+	for _, v := range desired {
+		wanted[v] = true
+	}
+	present := map[string]bool{}
+	sequence := []string{}
+	for _, i := range current {
+		v := i.Snippet.ResourceId.VideoId
+		if !wanted[v] || present[v] {
+			plan.deletes = append(plan.deletes, i)
+			continue
+		}
+		present[v] = true
+		sequence = append(sequence, v)
+	}
+	kept := []string{}
+	for i, v := range desired {
+		if present[v] {
+			kept = append(kept, v)
+		} else {
+			// the videos preceding it are all in place by now
+			plan.inserts = append(plan.inserts, syncMove{v, int64(i)})
+		}
+	}
+	plan.moves = planMoves(sequence, kept)
+	return plan
+}
+
+// planMoves returns the moves that reorder sequence as desired (both lists
+// must contain the same videos, or playlist items). The videos in the longest subsequence already
+// in the desired order stay still, so the number of moves is minimal; the others
+// are moved, in desired order, right after the video preceding them.
+func planMoves(sequence, desired []string) []syncMove {
+	rank := make(map[string]int, len(desired))
+	for i, v := range desired {
+		rank[v] = i
+	}
+	ranks := make([]int, len(sequence))
+	for i, v := range sequence {
+		ranks[i] = rank[v]
+	}
+	still := map[int]bool{}
+	for _, r := range longestIncreasing(ranks) {
+		still[r] = true
+	}
+	list := append([]string{}, sequence...)
+	moves := []syncMove{}
+	for r, v := range desired {
+		if still[r] {
+			continue
+		}
+		list = removeString(list, v)
+		pos := 0
+		if r > 0 {
+			pos = indexOfString(list, desired[r-1]) + 1
+		}
+		list = append(list[:pos], append([]string{v}, list[pos:]...)...)
+		moves = append(moves, syncMove{v, int64(pos)})
+	}
+	return moves
+}
+
+// longestIncreasing returns the values of the longest increasing subsequence of s.
+func longestIncreasing(s []int) []int {
+	// tails[k] is the index in s of the smallest tail of an increasing subsequence of length k+1
+	tails := []int{}
+	prev := make([]int, len(s))
+	for i, v := range s {
+		k := sort.Search(len(tails), func(k int) bool { return s[tails[k]] >= v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	out := make([]int, len(tails))
+	if len(tails) == 0 {
+		return out
+	}
+	for k, i := len(tails)-1, tails[len(tails)-1]; k >= 0; k, i = k-1, prev[i] {
+		out[k] = s[i]
+	}
+	return out
+}
+
+func indexOfString(s []string, v string) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeString(s []string, v string) []string {
+	if i := indexOfString(s, v); i >= 0 {
+		return append(s[:i], s[i+1:]...)
+	}
+	return s
+}
+
+// sortPlaylist loads the playlist items (and their details, if the keys need them), plans
+// the moves that sort them and applies them, sending each moved item as an Item (with status moved).
+// Items are planned by ID, so duplicated videos are moved independently.
+// The plan cost is checked against the quota budget before any move and, like syncPlaylist,
+// the sort stops at the first failure.
+func (tk *Toolkit) sortPlaylist(ctx context.Context, errors chan<- error, playlistId string, keys []SortKey) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		defer close(output)
+		tk.log("Loading playlist", playlistId, "...")
+		current, err := tk.playlistItems(ctx, playlistId)
+		if err != nil {
+			errors <- fmt.Errorf("playlist preload: %w", err)
+			return
+		}
+		items := make(chan Item)
+		go func() {
+			for _, i := range current {
+				send[Item](ctx, items, newPlaylistItemRecord(i, ""))
+			}
+			close(items)
+		}()
+		records := (<-chan Item)(items)
+		for _, k := range keys {
+			if videoDetailsFields[k.Field] {
+				records = tk.videoDetails(ctx, errors, records)
+				break
+			}
+		}
+		sorted := []Item{}
+		for r := range records {
+			sorted = append(sorted, r)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		sequence := []string{}
+		byId := map[string]*bigg.PlaylistItem{}
+		for _, i := range current {
+			sequence = append(sequence, i.Id)
+			byId[i.Id] = i
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return lessByKeys(sorted[i], sorted[j], keys)
+		})
+		desired := []string{}
+		for _, r := range sorted {
+			desired = append(desired, r.(*playlistItem).PlaylistItemId)
+		}
+		moves := planMoves(sequence, desired)
+		cost := uint32(len(moves)) * bigg.QUOTA_COST_UPDATE
+		tk.logf("Sort plan: %d moves (quota cost: %d units)\n", len(moves), cost)
+		if err := tk.checkQuota(cost); err != nil {
+			errors <- err
+			return
+		}
+
+		budget := tk.newBudgetGate(errors)
+		for _, m := range moves {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_UPDATE) {
+				return
+			}
+			i := byId[m.id]
+			tk.log("Moving video", i.Snippet.ResourceId.VideoId, "to position", m.position)
+			pli, err := tk.service.PlaylistItemsUpdate(ctx, i.Id, playlistId, i.Snippet.ResourceId.VideoId, m.position)
+			if err != nil {
+				errors <- err
+				return
+			}
+			send[Item](ctx, output, newPlaylistItemRecord(pli, statusMoved))
+		}
+	}()
+	return output
+}
+
+// syncPlaylist reads all the desired videos, plans the changes to the playlist and
+// applies them, sending each change as an Item (with status added, deleted or moved).
+// The plan cost is checked against the quota budget before any change.
+// Since the positions of the moves depend on the previous changes, the sync stops at
+// the first failure: running it again plans the remaining changes.
+func (tk *Toolkit) syncPlaylist(ctx context.Context, errors chan<- error, playlistId string, videoIds <-chan string) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		defer close(output)
+		desired := []string{}
+		seen := map[string]bool{}
+		for id := range videoIds {
+			if seen[id] {
+				tk.log("duplicate video", id, "ignored")
+				continue
+			}
+			seen[id] = true
+			desired = append(desired, id)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		tk.log("Loading playlist", playlistId, "...")
+		current, err := tk.playlistItems(ctx, playlistId)
+		if err != nil {
+			errors <- fmt.Errorf("playlist preload: %w", err)
+			return
+		}
+		plan := planSync(current, desired)
+		tk.logf("Sync plan: %d inserts, %d deletes, %d moves (quota cost: %d units)\n",
+			len(plan.inserts), len(plan.deletes), len(plan.moves), plan.cost())
+		if err := tk.checkQuota(plan.cost()); err != nil {
+			errors <- err
+			return
+		}
+
+		budget := tk.newBudgetGate(errors)
+		items := map[string]*bigg.PlaylistItem{}
+		for _, i := range current {
+			if _, ok := items[i.Snippet.ResourceId.VideoId]; !ok {
+				items[i.Snippet.ResourceId.VideoId] = i
+			}
+		}
+		for _, i := range plan.deletes {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_DELETE) {
+				return
+			}
+			tk.log("Deleting video", i.Snippet.ResourceId.VideoId)
+			if err := tk.service.PlaylistItemsDelete(ctx, i.Id); err != nil {
+				errors <- err
+				return
+			}
+			send[Item](ctx, output, newPlaylistItemRecord(i, statusDeleted))
+		}
+		for _, m := range plan.moves {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_UPDATE) {
+				return
+			}
+			tk.log("Moving video", m.id, "to position", m.position)
+			pli, err := tk.service.PlaylistItemsUpdate(ctx, items[m.id].Id, playlistId, m.id, m.position)
+			if err != nil {
+				errors <- err
+				return
+			}
+			send[Item](ctx, output, newPlaylistItemRecord(pli, statusMoved))
+		}
+		for _, m := range plan.inserts {
+			if ctx.Err() != nil || !budget.allows(bigg.QUOTA_COST_INSERT) {
+				return
+			}
+			tk.log("Adding video", m.id, "at position", m.position)
+			pli, err := tk.service.PlaylistItemsInsert(ctx, playlistId, m.id, m.position)
+			if err != nil {
+				errors <- err
+				return
+			}
+			send[Item](ctx, output, newPlaylistItemRecord(pli, statusAdded))
+		}
+	}()
+	return output
+}
@@ -1,7 +1,10 @@
 package youtubetoolkit
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/raffaelecassia/youtubetoolkit/bigg"
@@ -10,26 +13,41 @@ import (
 type Toolkit struct {
 	service   YoutubeService
 	logWriter io.Writer
+
+	hasBudget    bool
+	budget       uint32
+	budgetPolicy BudgetPolicy
 }
 
+// YoutubeService is the set of YouTube API operations used by the Toolkit.
+// Every method must stop and return ctx.Err() as soon as ctx is done,
+// including while blocked sending to an out channel.
 type YoutubeService interface {
-	SubscriptionsList(out chan<- *bigg.Sub) error
-	SubscriptionInsert(channelId string) (*bigg.Sub, error)
-	SubscriptionDelete(channelId string) error
-	PlaylistsList(out chan<- *bigg.Playlist) error
-	PlaylistInsert(title string) (*bigg.Playlist, error)
-	PlaylistDelete(playlistId string) error
-	PlaylistItemsList(id string, filter func(*bigg.PlaylistItem) (bool, error), out chan<- *bigg.PlaylistItem) error
-	PlaylistItemsInsert(playlistId, videoId string) (*bigg.PlaylistItem, error)
-	GetChannelInfo(id string) (*bigg.Channel, error)
+	SubscriptionsList(ctx context.Context, out chan<- *bigg.Sub) error
+	SubscriptionInsert(ctx context.Context, channelId string) (*bigg.Sub, error)
+	SubscriptionDelete(ctx context.Context, channelId string) error
+	SubscriptionDeleteById(ctx context.Context, subscriptionId string) error
+	PlaylistsList(ctx context.Context, out chan<- *bigg.Playlist) error
+	PlaylistInsert(ctx context.Context, meta bigg.PlaylistMetadata) (*bigg.Playlist, error)
+	PlaylistUpdate(ctx context.Context, playlistId string, update func(*bigg.PlaylistMetadata) error) (*bigg.Playlist, error)
+	PlaylistInfo(ctx context.Context, id string) (*bigg.Playlist, error)
+	PlaylistDelete(ctx context.Context, playlistId string) error
+	PlaylistItemsList(ctx context.Context, id string, filter func(*bigg.PlaylistItem) (bool, error), out chan<- *bigg.PlaylistItem) error
+	PlaylistItemsInsert(ctx context.Context, playlistId, videoId string, position int64) (*bigg.PlaylistItem, error)
+	PlaylistItemsDelete(ctx context.Context, playlistItemId string) error
+	PlaylistItemsUpdate(ctx context.Context, playlistItemId, playlistId, videoId string, position int64) (*bigg.PlaylistItem, error)
+	GetChannelInfo(ctx context.Context, id string) (*bigg.Channel, error)
+	ChannelsList(ctx context.Context, ids []string) ([]*bigg.Channel, error)
+	VideoInfo(ctx context.Context, id string) (*bigg.Video, error)
+	VideosList(ctx context.Context, ids []string) ([]*bigg.Video, error)
 }
 
 func New() *Toolkit {
-	return &Toolkit{nil, io.Discard}
+	return &Toolkit{logWriter: io.Discard}
 }
 
 func NewWithService(svc YoutubeService) *Toolkit {
-	return &Toolkit{svc, io.Discard}
+	return &Toolkit{service: svc, logWriter: io.Discard}
 }
 
 func (tk *Toolkit) SetService(service YoutubeService) {
@@ -39,36 +57,67 @@ func (tk *Toolkit) SetService(service YoutubeService) {
 // Subscriptions gets all channels from user subscription.
 // Flow: only sink is required
 func (tk *Toolkit) Subscriptions(opts ...FlowOption) error {
+	return tk.SubscriptionsContext(context.Background(), opts...)
+}
+
+// SubscriptionsContext is like Subscriptions but stops as soon as ctx is done.
+func (tk *Toolkit) SubscriptionsContext(ctx context.Context, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
+	ctx = flow.earlyStop(ctx)
 	errors, err := multiErrorsHandler()
 	subs := make(chan *bigg.Sub)
 	go func() {
-		errors <- tk.service.SubscriptionsList(subs)
+		errors <- tk.service.SubscriptionsList(ctx, subs)
 		close(subs)
 	}()
-	items := sub2item(subs)
-	flow.itemSink(errors, items)
+	items := sub2item(ctx, subs, "")
+	flow.itemSink(ctx, errors, items)
 	close(errors)
-	return <-err
+	return flow.result(<-err)
 }
 
 // Subscribe adds channels to user subscriptions.
-// Flow: source and sink are required
+// With SkipExisting, the current subscriptions are loaded first (1 unit every 50 channels)
+// to avoid paying 50 units for each channel already subscribed.
+// Flow: source and sink are required, journal and skip existing are optional
 func (tk *Toolkit) Subscribe(opts ...FlowOption) error {
+	return tk.SubscribeContext(context.Background(), opts...)
+}
+
+// SubscribeContext is like Subscribe but stops as soon as ctx is done.
+func (tk *Toolkit) SubscribeContext(ctx context.Context, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
+	flow.journal = tk.flowJournal(flow.journal)
 	errors, err := multiErrorsHandler()
-	channelIds := flow.stringSource(errors)
-	subs := tk.channels2newsubscriptions(errors, channelIds)
-	items := sub2item(subs)
-	flow.itemSink(errors, items)
+	channelIds := flow.stringSource(ctx, errors)
+	channelIds = tk.skipCompleted(ctx, flow.journal, channelIds)
+	var skipped <-chan *bigg.Sub
+	if flow.skipExisting {
+		channelIds, skipped = tk.skipSubscribed(ctx, errors, channelIds, flow.journal)
+	}
+	channelIds = tk.budgetEstimate(ctx, errors, channelIds, bigg.QUOTA_COST_INSERT)
+	subs := tk.channels2newsubscriptions(ctx, errors, channelIds, flow.journal)
+	items := sub2item(ctx, subs, statusAdded)
+	if skipped != nil {
+		items = mergeItems(ctx, items, sub2item(ctx, skipped, statusSkipped))
+	}
+	flow.itemSink(ctx, errors, items)
 	close(errors)
 	return <-err
 }
 
 // Unsubscribe removes channel from user subscriptions.
 func (tk *Toolkit) Unsubscribe(channelId string) error {
+	return tk.UnsubscribeContext(context.Background(), channelId)
+}
+
+// UnsubscribeContext is like Unsubscribe but stops as soon as ctx is done.
+func (tk *Toolkit) UnsubscribeContext(ctx context.Context, channelId string) error {
+	if err := tk.checkQuota(bigg.QUOTA_COST_SUBSCRIPTION_DELETE); err != nil {
+		return err
+	}
 	tk.logf("unsubscribing from %s... ", channelId)
-	err := tk.service.SubscriptionDelete(channelId)
+	err := tk.service.SubscriptionDelete(ctx, channelId)
 	if err != nil {
 		tk.log("fail!")
 		return err
@@ -77,82 +126,378 @@ func (tk *Toolkit) Unsubscribe(channelId string) error {
 	return nil
 }
 
+// SubscriptionsDiff compares the subscriptions of two accounts: tk's (A) and other's (B).
+// Channels are sent to the sink with the Status "only-a", "only-b" or "common".
+// Flow: only sink is required
+func (tk *Toolkit) SubscriptionsDiff(other *Toolkit, opts ...FlowOption) error {
+	return tk.SubscriptionsDiffContext(context.Background(), other, opts...)
+}
+
+// SubscriptionsDiffContext is like SubscriptionsDiff but stops as soon as ctx is done.
+func (tk *Toolkit) SubscriptionsDiffContext(ctx context.Context, other *Toolkit, opts ...FlowOption) error {
+	flow := options2flowconfig(opts...)
+	errors, err := multiErrorsHandler()
+	items := tk.subscriptionsDiff(ctx, errors, other)
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return <-err
+}
+
+// SyncSubscriptions subscribes tk's account to the channels subscribed by the from
+// account and not by tk's one. With Prune, tk's subscriptions not in the from
+// account are removed too, so that tk's account mirrors the from one.
+// Changes are sent to the sink with the Status "added" or "deleted".
+// The plan cost is logged and checked against the quota budget before any change.
+// Flow: only sink is required, prune is optional
+func (tk *Toolkit) SyncSubscriptions(from *Toolkit, opts ...FlowOption) error {
+	return tk.SyncSubscriptionsContext(context.Background(), from, opts...)
+}
+
+// SyncSubscriptionsContext is like SyncSubscriptions but stops as soon as ctx is done.
+func (tk *Toolkit) SyncSubscriptionsContext(ctx context.Context, from *Toolkit, opts ...FlowOption) error {
+	flow := options2flowconfig(opts...)
+	errors, err := multiErrorsHandler()
+	toAdd, toDelete := tk.planSubscriptionsSync(ctx, errors, from, flow.prune)
+	added := tk.channels2newsubscriptions(ctx, errors, toAdd, nil)
+	deleted := tk.subscriptions2unsubscriptions(ctx, errors, toDelete)
+	items := mergeItems(ctx, sub2item(ctx, added, statusAdded), sub2item(ctx, deleted, statusDeleted))
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return <-err
+}
+
 // Playlists gets all user playlists.
 // Flow: only sink is required
 func (tk *Toolkit) Playlists(opts ...FlowOption) error {
+	return tk.PlaylistsContext(context.Background(), opts...)
+}
+
+// PlaylistsContext is like Playlists but stops as soon as ctx is done.
+func (tk *Toolkit) PlaylistsContext(ctx context.Context, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
+	ctx = flow.earlyStop(ctx)
 	errors, err := multiErrorsHandler()
 	pls := make(chan *bigg.Playlist)
 	go func() {
-		errors <- tk.service.PlaylistsList(pls)
+		errors <- tk.service.PlaylistsList(ctx, pls)
 		close(pls)
 	}()
-	items := playlist2item(pls)
-	flow.itemSink(errors, items)
+	items := playlist2item(ctx, pls)
+	flow.itemSink(ctx, errors, items)
 	close(errors)
-	return <-err
+	return flow.result(<-err)
 }
 
-// Playlist gets a playlist's videos.
+// Playlist gets a playlist's videos (with their details if VideoDetails is set).
 // Flow: only sink is required
 func (tk *Toolkit) Playlist(playlistId string, opts ...FlowOption) error {
+	return tk.PlaylistContext(context.Background(), playlistId, opts...)
+}
+
+// PlaylistContext is like Playlist but stops as soon as ctx is done.
+func (tk *Toolkit) PlaylistContext(ctx context.Context, playlistId string, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
+	ctx = flow.earlyStop(ctx)
 	errors, err := multiErrorsHandler()
 	pls := make(chan *bigg.PlaylistItem)
 	go func() {
-		errors <- tk.service.PlaylistItemsList(playlistId, allPlaylistItems(), pls)
+		errors <- tk.service.PlaylistItemsList(ctx, playlistId, allPlaylistItems(), pls)
 		close(pls)
 	}()
-	items := playlistItem2item(pls)
-	flow.itemSink(errors, items)
+	items := playlistItem2item(ctx, pls, "")
+	if flow.videoDetails {
+		items = tk.videoDetails(ctx, errors, items)
+	}
+	flow.itemSink(ctx, errors, items)
 	close(errors)
-	return <-err
+	return flow.result(<-err)
 }
 
 // NewPlaylist creates a new private playlist.
 // Returns the playlist ID or error.
 func (tk *Toolkit) NewPlaylist(title string) (string, error) {
-	pl, err := tk.service.PlaylistInsert(title)
+	return tk.NewPlaylistContext(context.Background(), title)
+}
+
+// NewPlaylistContext is like NewPlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) NewPlaylistContext(ctx context.Context, title string) (string, error) {
+	return tk.NewPlaylistWithMetadataContext(ctx, bigg.PlaylistMetadata{Title: title})
+}
+
+// NewPlaylistWithMetadata creates a new playlist with a title and optionally a description,
+// tags, a language and a privacy (private, unless the metadata has another PrivacyStatus).
+// Returns the playlist ID or error.
+func (tk *Toolkit) NewPlaylistWithMetadata(meta bigg.PlaylistMetadata) (string, error) {
+	return tk.NewPlaylistWithMetadataContext(context.Background(), meta)
+}
+
+// NewPlaylistWithMetadataContext is like NewPlaylistWithMetadata but stops as soon as ctx is done.
+func (tk *Toolkit) NewPlaylistWithMetadataContext(ctx context.Context, meta bigg.PlaylistMetadata) (string, error) {
+	if err := validatePlaylistMetadata(&meta); err != nil {
+		return "", err
+	}
+	if err := tk.checkQuota(bigg.QUOTA_COST_INSERT); err != nil {
+		return "", err
+	}
+	pl, err := tk.service.PlaylistInsert(ctx, meta)
 	if err != nil {
 		return "", err
 	}
 	return pl.Id, nil
 }
 
+// UpdatePlaylist changes the metadata of a user playlist with the update function,
+// that receives the current metadata (the playlist is read first: 51 units).
+func (tk *Toolkit) UpdatePlaylist(playlistId string, update func(*bigg.PlaylistMetadata)) error {
+	return tk.UpdatePlaylistContext(context.Background(), playlistId, update)
+}
+
+// UpdatePlaylistContext is like UpdatePlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) UpdatePlaylistContext(ctx context.Context, playlistId string, update func(*bigg.PlaylistMetadata)) error {
+	if err := tk.checkQuota(bigg.QUOTA_COST_LIST + bigg.QUOTA_COST_UPDATE); err != nil {
+		return err
+	}
+	_, err := tk.service.PlaylistUpdate(ctx, playlistId, func(meta *bigg.PlaylistMetadata) error {
+		update(meta)
+		return validatePlaylistMetadata(meta)
+	})
+	return err
+}
+
+// validatePlaylistMetadata checks the metadata before a write.
+func validatePlaylistMetadata(meta *bigg.PlaylistMetadata) error {
+	if strings.TrimSpace(meta.Title) == "" {
+		return fmt.Errorf("playlist title is mandatory")
+	}
+	switch meta.PrivacyStatus {
+	case "", "public", "unlisted", "private":
+		return nil
+	}
+	return fmt.Errorf("invalid playlist privacy %q (public, unlisted or private)", meta.PrivacyStatus)
+}
+
 // DeletePlaylist deletes a user playlist.
 func (tk *Toolkit) DeletePlaylist(playlistId string) error {
-	return tk.service.PlaylistDelete(playlistId)
+	return tk.DeletePlaylistContext(context.Background(), playlistId)
 }
 
-// AddVideoToPlaylist adds videos to a playlist.
-// Flow: source and sink are required
+// DeletePlaylistContext is like DeletePlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) DeletePlaylistContext(ctx context.Context, playlistId string) error {
+	if err := tk.checkQuota(bigg.QUOTA_COST_DELETE); err != nil {
+		return err
+	}
+	return tk.service.PlaylistDelete(ctx, playlistId)
+}
+
+// ClonePlaylist copies a playlist (a user own playlist or a public one) into a new playlist
+// with the same videos in the same order, skipping the deleted and private videos and the duplicates.
+// The title defaults to the one of the source playlist. The source items are read first
+// (1 unit every 50 videos), then the playlist is created (50 units) and the videos are
+// appended (50 units each). The cost is logged and checked against the quota budget first.
+// With a journal, the new playlist is recorded: resuming it, the clone continues in the
+// same playlist, adding only the videos not done yet (in dry-run, the placeholder playlist is not recorded).
+// The added items are sent to the sink with the Status "added". Returns the new playlist ID.
+// Flow: only sink is required, journal is optional
+func (tk *Toolkit) ClonePlaylist(sourceId string, meta bigg.PlaylistMetadata, opts ...FlowOption) (string, error) {
+	return tk.ClonePlaylistContext(context.Background(), sourceId, meta, opts...)
+}
+
+// ClonePlaylistContext is like ClonePlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) ClonePlaylistContext(ctx context.Context, sourceId string, meta bigg.PlaylistMetadata, opts ...FlowOption) (string, error) {
+	flow := options2flowconfig(opts...)
+	flow.journal = tk.flowJournal(flow.journal)
+	tk.log("Loading playlist", sourceId, "...")
+	source, err := tk.playlistItems(ctx, sourceId)
+	if err != nil {
+		return "", fmt.Errorf("playlist preload: %w", err)
+	}
+	videoIds := tk.cloneableVideos(source)
+
+	playlistId := flow.journal.Target(sourceId)
+	pending := 0
+	for _, id := range videoIds {
+		if !flow.journal.Completed(id) {
+			pending++
+		}
+	}
+	cost := uint32(pending) * bigg.QUOTA_COST_INSERT
+	if playlistId == "" {
+		cost += bigg.QUOTA_COST_INSERT
+	}
+	tk.logf("Clone plan: %d of %d videos to add (quota cost: %d units)\n", pending, len(source), cost)
+	if err := tk.checkQuota(cost); err != nil {
+		return "", err
+	}
+
+	if playlistId != "" {
+		tk.log("Resuming the clone into playlist", playlistId)
+	} else {
+		if meta.Title == "" {
+			pl, err := tk.service.PlaylistInfo(ctx, sourceId)
+			if err != nil {
+				return "", err
+			}
+			meta.Title = pl.Snippet.Title
+		}
+		playlistId, err = tk.NewPlaylistWithMetadataContext(ctx, meta)
+		if err != nil {
+			return "", err
+		}
+		if err := flow.journal.RecordTarget(sourceId, playlistId); err != nil {
+			return playlistId, err
+		}
+	}
+
+	errors, errc := multiErrorsHandler()
+	ids := make(chan string)
+	go func() {
+		for _, id := range videoIds {
+			if !send(ctx, ids, id) {
+				break
+			}
+		}
+		close(ids)
+	}()
+	pendingIds := tk.skipCompleted(ctx, flow.journal, ids)
+	pendingIds = tk.budgetEstimate(ctx, errors, pendingIds, bigg.QUOTA_COST_INSERT)
+	plitems := tk.videos2playlist(ctx, errors, playlistId, pendingIds, bigg.POSITION_APPEND, flow.journal)
+	items := playlistItem2item(ctx, plitems, statusAdded)
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return playlistId, <-errc
+}
+
+// AddVideoToPlaylist adds videos to a playlist, in input order: at the top of the playlist
+// (position 0) or, with InsertAt, from another position or appended (bigg.POSITION_APPEND).
+// With SkipExisting, the playlist items are loaded first (1 unit every 50 videos)
+// and only the videos not already in the playlist are added.
+// Flow: source and sink are required, journal and skip existing are optional
 func (tk *Toolkit) AddVideoToPlaylist(playlistId string, opts ...FlowOption) error {
+	return tk.AddVideoToPlaylistContext(context.Background(), playlistId, opts...)
+}
+
+// AddVideoToPlaylistContext is like AddVideoToPlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) AddVideoToPlaylistContext(ctx context.Context, playlistId string, opts ...FlowOption) error {
+	flow := options2flowconfig(opts...)
+	flow.journal = tk.flowJournal(flow.journal)
+	errors, err := multiErrorsHandler()
+	videoIds := flow.stringSource(ctx, errors)
+	videoIds = tk.skipCompleted(ctx, flow.journal, videoIds)
+	var skipped <-chan *bigg.PlaylistItem
+	if flow.skipExisting {
+		videoIds, skipped = tk.skipInPlaylist(ctx, errors, playlistId, videoIds, flow.journal)
+	}
+	videoIds = tk.budgetEstimate(ctx, errors, videoIds, bigg.QUOTA_COST_INSERT)
+	position := int64(0)
+	if flow.insertAt != nil {
+		position = *flow.insertAt
+	}
+	plitems := tk.videos2playlist(ctx, errors, playlistId, videoIds, position, flow.journal)
+	items := playlistItem2item(ctx, plitems, statusAdded)
+	if skipped != nil {
+		items = mergeItems(ctx, items, playlistItem2item(ctx, skipped, statusSkipped))
+	}
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return <-err
+}
+
+// RemoveVideoFromPlaylist removes videos from a playlist. The source sends video IDs
+// (11 chars, all the items of the video are removed) or playlist item IDs: the playlist
+// items are loaded first (1 unit every 50 videos) to resolve them, then each item is
+// deleted (50 units). The removed items are sent to the sink with the Status "deleted".
+// Flow: source and sink are required, journal is optional
+func (tk *Toolkit) RemoveVideoFromPlaylist(playlistId string, opts ...FlowOption) error {
+	return tk.RemoveVideoFromPlaylistContext(context.Background(), playlistId, opts...)
+}
+
+// RemoveVideoFromPlaylistContext is like RemoveVideoFromPlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) RemoveVideoFromPlaylistContext(ctx context.Context, playlistId string, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
+	flow.journal = tk.flowJournal(flow.journal)
 	errors, err := multiErrorsHandler()
-	videoIds := flow.stringSource(errors)
-	plitems := tk.videos2playlist(errors, playlistId, videoIds)
-	items := playlistItem2item(plitems)
-	flow.itemSink(errors, items)
+	ids := flow.stringSource(ctx, errors)
+	ids = tk.skipCompleted(ctx, flow.journal, ids)
+	ids = tk.budgetEstimate(ctx, errors, ids, bigg.QUOTA_COST_DELETE)
+	plitems := tk.removeFromPlaylist(ctx, errors, playlistId, ids, flow.journal)
+	items := playlistItem2item(ctx, plitems, statusDeleted)
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return <-err
+}
+
+// SyncPlaylist makes a playlist contain exactly the videos from the source, in the
+// same order: missing videos are added, extra ones deleted and the others moved
+// where needed (each change costs 50 units, moves are kept to the minimum).
+// The changes are sent to the sink with the Status "added", "deleted" or "moved".
+// The plan cost is logged and checked against the quota budget before any change.
+// Flow: source and sink are required
+func (tk *Toolkit) SyncPlaylist(playlistId string, opts ...FlowOption) error {
+	return tk.SyncPlaylistContext(context.Background(), playlistId, opts...)
+}
+
+// SyncPlaylistContext is like SyncPlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) SyncPlaylistContext(ctx context.Context, playlistId string, opts ...FlowOption) error {
+	flow := options2flowconfig(opts...)
+	errors, err := multiErrorsHandler()
+	videoIds := flow.stringSource(ctx, errors)
+	items := tk.syncPlaylist(ctx, errors, playlistId, videoIds)
+	flow.itemSink(ctx, errors, items)
+	close(errors)
+	return <-err
+}
+
+// SortPlaylist reorders a playlist by the keys (see SortBy), keeping its ID: only the items
+// out of order are moved (50 units each, moves are kept to the minimum). The playlist items
+// are loaded first (1 unit every 50 videos), with their details when sorting by them (eg. Duration).
+// The moved items are sent to the sink with the Status "moved".
+// The plan cost is logged and checked against the quota budget before any move.
+// Flow: only sink is required
+func (tk *Toolkit) SortPlaylist(playlistId string, keys []SortKey, opts ...FlowOption) error {
+	return tk.SortPlaylistContext(context.Background(), playlistId, keys, opts...)
+}
+
+// SortPlaylistContext is like SortPlaylist but stops as soon as ctx is done.
+func (tk *Toolkit) SortPlaylistContext(ctx context.Context, playlistId string, keys []SortKey, opts ...FlowOption) error {
+	flow := options2flowconfig(opts...)
+	errors, err := multiErrorsHandler()
+	items := tk.sortPlaylist(ctx, errors, playlistId, keys)
+	flow.itemSink(ctx, errors, items)
 	close(errors)
 	return <-err
 }
 
 // CSVLastUploads gets the latest channels' video uploads since the time argument.
-// Videos are sorted by the published date (oldest first).
+// Videos are sorted by the published date (oldest first), with their details if VideoDetails is set.
+// Some kinds of videos can be skipped with ExcludeVideos and VideoDuration.
 // Flow: source and sink are required
 func (tk *Toolkit) LastUploads(since time.Time, opts ...FlowOption) error {
+	return tk.LastUploadsContext(context.Background(), since, opts...)
+}
+
+// LastUploadsContext is like LastUploads but stops as soon as ctx is done.
+func (tk *Toolkit) LastUploadsContext(ctx context.Context, since time.Time, opts ...FlowOption) error {
 	flow := options2flowconfig(opts...)
 	errors, err := multiErrorsHandler()
 
 	filter := sinceDatePlaylistItems(since)
+	variants := flow.uploadsVariants()
 
-	channelIds := flow.stringSource(errors)
+	channelIds := flow.stringSource(ctx, errors)
+	// at least a page of each uploads playlist (or variant) for each channel (channel lookups are derived or batched)
+	channelIds = tk.budgetEstimate(ctx, errors, channelIds, uploadsCost(variants))
 	// fetch all video uploads using three parallel go routines
-	playlistItems := tk.channels2channelvideouploads(errors, channelIds, filter, 3)
-	sorted := sortPlaylistItemByPublishedAt(playlistItems)
+	items := tk.channels2channelvideouploads(ctx, errors, channelIds, variants, filter, 3)
+	if flow.videoDetails || flow.needsVideoDetails() {
+		items = tk.videoDetails(ctx, errors, items)
+	}
+	// filters before buffering everything to sort
+	if videoFilter := flow.videoFilter(); videoFilter != nil {
+		items = tk.filterVideos(ctx, items, videoFilter)
+	}
+	sorted := sortPlaylistItemByPublishedAt(ctx, items)
 
-	items := playlistItem2item(sorted)
-	flow.itemSink(errors, items)
+	flow.itemSink(ctx, errors, sorted)
 
 	close(errors)
 	return <-err
@@ -2,14 +2,27 @@ package youtubetoolkit_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/raffaelecassia/youtubetoolkit"
 	"github.com/raffaelecassia/youtubetoolkit/bigg"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/youtube/v3"
 )
 
@@ -75,6 +88,1275 @@ func TestCSVBulkSubscribe(t *testing.T) {
 	})
 }
 
+func TestSubscribeQuotaBudget(t *testing.T) {
+	in := "A\nB\nC\n"
+
+	t.Run("refuses when the estimate exceeds the budget", func(t *testing.T) {
+		f := newFakeService()
+		s := youtubetoolkit.NewWithService(f)
+		s.SetQuotaBudget(120, youtubetoolkit.BudgetRefuse)
+
+		err := s.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)), youtubetoolkit.NullSink())
+		if !errors.Is(err, youtubetoolkit.ErrQuotaBudgetExceeded) {
+			t.Errorf("want ErrQuotaBudgetExceeded, got: %v", err)
+		}
+		if len(f.subinsert) != 0 {
+			t.Errorf("want no inserts, got: %s", f.subinsert)
+		}
+	})
+
+	t.Run("truncates the input to fit the budget", func(t *testing.T) {
+		f := newFakeService()
+		s := youtubetoolkit.NewWithService(f)
+		s.SetQuotaBudget(120, youtubetoolkit.BudgetTruncate)
+
+		err := s.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)), youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		want := []string{"A", "B"}
+		if !reflect.DeepEqual(want, f.subinsert) {
+			t.Errorf("want: %s got: %s", want, f.subinsert)
+		}
+	})
+
+	t.Run("stops mid-flow when the budget is reached", func(t *testing.T) {
+		f := newFakeService()
+		f.extracost = 20 // real costs higher than the estimate
+		s := youtubetoolkit.NewWithService(f)
+		s.SetQuotaBudget(150, youtubetoolkit.BudgetRefuse)
+
+		err := s.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)), youtubetoolkit.NullSink())
+		if !errors.Is(err, youtubetoolkit.ErrQuotaBudgetExceeded) {
+			t.Errorf("want ErrQuotaBudgetExceeded, got: %v", err)
+		}
+		want := []string{"A", "B"}
+		if !reflect.DeepEqual(want, f.subinsert) {
+			t.Errorf("want: %s got: %s", want, f.subinsert)
+		}
+	})
+}
+
+func TestDryRunAddVideoToPlaylist(t *testing.T) {
+	t.Run("records the inserts without calling the service", func(t *testing.T) {
+		f := newFakeService()
+		d := youtubetoolkit.NewDryRunService(f)
+		s := youtubetoolkit.NewWithService(d)
+		w := &bytes.Buffer{}
+
+		err := s.AddVideoToPlaylist("PL1",
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("V1\nV2\n")),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))
+		if err != nil {
+			t.Error(err)
+		}
+
+		if diff := cmp.Diff("V1\nV2\n", w.String()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		want := []youtubetoolkit.DryRunOp{
+			{Action: "add at position 0 to playlist PL1 video", Target: "V1", Cost: 50},
+			{Action: "add at position 1 to playlist PL1 video", Target: "V2", Cost: 50},
+		}
+		if diff := cmp.Diff(want, d.Operations()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if got := d.GetCost(); got != 100 {
+			t.Errorf("want projected cost 100, got: %d", got)
+		}
+	})
+}
+
+func TestSubscribeSkipExisting(t *testing.T) {
+	t.Run("skips channels already subscribed and duplicates", func(t *testing.T) {
+		f := newFakeService()
+		f.subslist = []bigg.Sub{newSub("B", "TB")}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		in := "A\nB\nA\nC\n"
+		err := s.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)),
+			youtubetoolkit.CSVSink(w, &[]string{"ChannelId", "Status"}),
+			youtubetoolkit.SkipExisting())
+		if err != nil {
+			t.Error(err)
+		}
+
+		want := []string{"A", "C"}
+		if !reflect.DeepEqual(want, f.subinsert) {
+			t.Errorf("want: %s got: %s", want, f.subinsert)
+		}
+		got := strings.Split(strings.TrimSpace(w.String()), "\n")
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"A,added", "B,skipped", "C,added"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSubscribeJournal(t *testing.T) {
+	t.Run("resumes skipping the completed inputs", func(t *testing.T) {
+		f := newFakeService()
+		s := youtubetoolkit.NewWithService(f)
+
+		previous := `{"id":"A","status":"done"}
+{"id":"B","status":"failed","reason":"quotaExceeded"}
+{"id":"C","status":"skipped","reason":"already subscribed"}
+`
+		w := &bytes.Buffer{}
+		j, err := youtubetoolkit.NewJournal(w, strings.NewReader(previous))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		in := "A\nB\nC\nD\n"
+		err = s.Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)),
+			youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+		if err != nil {
+			t.Error(err)
+		}
+
+		want := []string{"B", "D"}
+		if !reflect.DeepEqual(want, f.subinsert) {
+			t.Errorf("want: %s got: %s", want, f.subinsert)
+		}
+		got := []string{}
+		for _, line := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+			var e youtubetoolkit.JournalEntry
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, e.Id+":"+string(e.Status))
+		}
+		if diff := cmp.Diff([]string{"B:done", "D:done"}, got); diff != "" {
+			t.Errorf("journal mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestDryRunJournal(t *testing.T) {
+	t.Run("a dry-run doesn't record the journal resumed by a real run", func(t *testing.T) {
+		f := newFakeService()
+		d := youtubetoolkit.NewDryRunService(f)
+		journal := &bytes.Buffer{}
+		j, err := youtubetoolkit.NewJournal(journal, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = youtubetoolkit.NewWithService(d).AddVideoToPlaylist("PL1",
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("V1\nV2\n")),
+			youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+		if err != nil {
+			t.Error(err)
+		}
+		if journal.Len() != 0 {
+			t.Errorf("want an empty journal, got: %s", journal.String())
+		}
+
+		j, err = youtubetoolkit.NewJournal(&bytes.Buffer{}, bytes.NewReader(journal.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = youtubetoolkit.NewWithService(f).AddVideoToPlaylist("PL1",
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("V1\nV2\n")),
+			youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]string{"V1", "V2"}, f.plinsert); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("a dry-run resuming a journal skips the completed inputs", func(t *testing.T) {
+		f := newFakeService()
+		d := youtubetoolkit.NewDryRunService(f)
+		w := &bytes.Buffer{}
+		j, err := youtubetoolkit.NewJournal(w, strings.NewReader(`{"id":"A","status":"done"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = youtubetoolkit.NewWithService(d).Subscribe(youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("A\nB\n")),
+			youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+		if err != nil {
+			t.Error(err)
+		}
+		want := []youtubetoolkit.DryRunOp{{Action: "subscribe to channel", Target: "B", Cost: 50}}
+		if diff := cmp.Diff(want, d.Operations()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if w.Len() != 0 {
+			t.Errorf("want nothing recorded, got: %s", w.String())
+		}
+	})
+}
+
+func TestClonePlaylist(t *testing.T) {
+	source := func() *fakeService {
+		f := newFakeService()
+		f.playlists = []bigg.Playlist{newPlaylist("SRC", "Someone's playlist", 5)}
+		f.playlistitems = map[string][]bigg.PlaylistItem{"SRC": {
+			newPlaylistItem("A", "TA", "CH1", "", ""),
+			newPlaylistItem("X", "Deleted video", "", "", ""),
+			newPlaylistItem("B", "TB", "CH2", "", ""),
+			newPlaylistItem("A", "TA", "CH1", "", ""),
+			newPlaylistItem("C", "TC", "CH1", "", ""),
+		}}
+		return f
+	}
+
+	t.Run("clones the videos in order, skipping deleted ones and duplicates", func(t *testing.T) {
+		f := source()
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		id, err := s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status"}))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]string{"A", "B", "C"}, playlistVideoIds(f, id)); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff("A,added\nB,added\nC,added\n", w.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if len(f.playlists) != 2 || f.playlists[1].Snippet.Title != "Someone's playlist" {
+			t.Errorf("want a single clone with the source title, got: %d playlists", len(f.playlists))
+		}
+	})
+
+	t.Run("applies the budget policy to the plan cost", func(t *testing.T) {
+		for _, policy := range []youtubetoolkit.BudgetPolicy{youtubetoolkit.BudgetRefuse, youtubetoolkit.BudgetTruncate} {
+			f := source()
+			s := youtubetoolkit.NewWithService(f)
+			s.SetQuotaBudget(3*bigg.QUOTA_COST_INSERT, policy)
+			_, err := s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.NullSink())
+			if !errors.Is(err, youtubetoolkit.ErrQuotaBudgetExceeded) {
+				t.Errorf("policy %d: want ErrQuotaBudgetExceeded, got: %v", policy, err)
+			}
+			if len(f.playlists) != 1 {
+				t.Errorf("policy %d: want no clone, got: %d playlists", policy, len(f.playlists))
+			}
+		}
+
+		f := source()
+		s := youtubetoolkit.NewWithService(f)
+		s.SetQuotaBudget(3*bigg.QUOTA_COST_INSERT, youtubetoolkit.BudgetWarn)
+		log := &bytes.Buffer{}
+		s.SetLogWriter(log)
+		id, err := s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		if !strings.Contains(log.String(), "Warning: quota budget exceeded: 200 units needed") {
+			t.Errorf("want a budget warning, got: %s", log.String())
+		}
+		if diff := cmp.Diff([]string{"A", "B", "C"}, playlistVideoIds(f, id)); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("resumes in the same playlist", func(t *testing.T) {
+		f := source()
+		f.playlists = append(f.playlists, newPlaylist("CLONE", "Someone's playlist", 1))
+		f.playlistitems["CLONE"] = playlistOf("A")
+		previous := `{"id":"SRC","status":"done","target":"CLONE"}
+{"id":"A","status":"done"}
+{"id":"B","status":"failed","reason":"quotaExceeded"}
+`
+		j, err := youtubetoolkit.NewJournal(&bytes.Buffer{}, strings.NewReader(previous))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		resumed, err := s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status"}),
+			youtubetoolkit.WithJournal(j))
+		if err != nil {
+			t.Error(err)
+		}
+		if resumed != "CLONE" {
+			t.Errorf("want the clone resumed in CLONE, got: %s", resumed)
+		}
+		if diff := cmp.Diff([]string{"A", "B", "C"}, playlistVideoIds(f, "CLONE")); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff("B,added\nC,added\n", w.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if len(f.playlists) != 2 {
+			t.Errorf("want no new playlist, got: %d playlists", len(f.playlists))
+		}
+	})
+}
+
+func TestClonePlaylistDryRun(t *testing.T) {
+	f := newFakeService()
+	f.playlists = []bigg.Playlist{newPlaylist("SRC", "Someone's playlist", 2)}
+	f.playlistitems = map[string][]bigg.PlaylistItem{"SRC": {
+		newPlaylistItem("A", "TA", "CH1", "", ""),
+		newPlaylistItem("B", "TB", "CH2", "", ""),
+	}}
+
+	// the dry-run playlist is not recorded as the clone target
+	journal := &bytes.Buffer{}
+	j, err := youtubetoolkit.NewJournal(journal, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := youtubetoolkit.NewWithService(youtubetoolkit.NewDryRunService(f))
+	id, err := s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+	if err != nil {
+		t.Error(err)
+	}
+	if id != youtubetoolkit.DRYRUN_PLAYLIST_ID || journal.Len() != 0 {
+		t.Errorf("want a dry-run clone without journal, got: %s %s", id, journal.String())
+	}
+
+	// resumed for real, it clones into a new playlist
+	j, err = youtubetoolkit.NewJournal(&bytes.Buffer{}, bytes.NewReader(journal.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = youtubetoolkit.NewWithService(f)
+	id, err = s.ClonePlaylist("SRC", bigg.PlaylistMetadata{}, youtubetoolkit.NullSink(), youtubetoolkit.WithJournal(j))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]string{"A", "B"}, playlistVideoIds(f, id)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAddVideoToPlaylistPosition(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []youtubetoolkit.FlowOption
+		want []string
+	}{
+		{"prepends by default", nil, []string{"V1", "V2", "V3", "A", "B"}},
+		{"prepends", []youtubetoolkit.FlowOption{youtubetoolkit.InsertAt(0)}, []string{"V1", "V2", "V3", "A", "B"}},
+		{"appends", []youtubetoolkit.FlowOption{youtubetoolkit.InsertAt(bigg.POSITION_APPEND)}, []string{"A", "B", "V1", "V2", "V3"}},
+		{"inserts at a position", []youtubetoolkit.FlowOption{youtubetoolkit.InsertAt(1)}, []string{"A", "V1", "V2", "V3", "B"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeService()
+			f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf("A", "B")}
+			s := youtubetoolkit.NewWithService(f)
+
+			opts := append(tc.opts, youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("V1\nV2\nV3\n")),
+				youtubetoolkit.NullSink())
+			if err := s.AddVideoToPlaylist("PL1", opts...); err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(tc.want, playlistVideoIds(f, "PL1")); diff != "" {
+				t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAddVideoToPlaylistSkipExisting(t *testing.T) {
+	t.Run("skips videos already in the playlist and duplicates", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{
+			"PL1": {newPlaylistItem("V2", "T2", "", "", "")},
+		}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		in := "V1\nV2\nV3\nV1\n"
+		err := s.AddVideoToPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status"}),
+			youtubetoolkit.SkipExisting())
+		if err != nil {
+			t.Error(err)
+		}
+
+		want := []string{"V1", "V3"}
+		if !reflect.DeepEqual(want, f.plinsert) {
+			t.Errorf("want: %s got: %s", want, f.plinsert)
+		}
+		got := strings.Split(strings.TrimSpace(w.String()), "\n")
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"V1,added", "V2,skipped", "V3,added"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRemoveVideoFromPlaylist(t *testing.T) {
+	f := newFakeService()
+	items := []bigg.PlaylistItem{
+		newPlaylistItem("V1xxxxxxxxx", "T1", "", "", ""),
+		newPlaylistItem("V2xxxxxxxxx", "T2", "", "", ""),
+		newPlaylistItem("V3xxxxxxxxx", "T3", "", "", ""),
+		newPlaylistItem("V1xxxxxxxxx", "T1", "", "", ""),
+	}
+	for i := range items {
+		items[i].Id = fmt.Sprintf("PLI%d-0123456789abcdefghijklmnopqrstu", i)
+	}
+	f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": items}
+	s := youtubetoolkit.NewWithService(f)
+	w := &bytes.Buffer{}
+
+	// a video id removes all its items, a playlist item id only that item
+	in := "V1xxxxxxxxx\nPLI2-0123456789abcdefghijklmnopqrstu\nV9xxxxxxxxx\n"
+	err := s.RemoveVideoFromPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)),
+		youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status"}))
+	if err != nil {
+		t.Error(err)
+	}
+	want := "V1xxxxxxxxx,deleted\nV1xxxxxxxxx,deleted\nV3xxxxxxxxx,deleted\n"
+	if diff := cmp.Diff(want, w.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	left := []string{}
+	for _, i := range f.playlistitems["PL1"] {
+		left = append(left, i.Snippet.ResourceId.VideoId)
+	}
+	if diff := cmp.Diff([]string{"V2xxxxxxxxx"}, left); diff != "" {
+		t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+	}
+	if want := 3 * bigg.QUOTA_COST_DELETE; f.cost != want {
+		t.Errorf("want cost %d, got: %d", want, f.cost)
+	}
+}
+
+func TestSyncPlaylist(t *testing.T) {
+	t.Run("inserts, deletes and moves", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf("A", "B", "C", "D", "B")}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		err := s.SyncPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("D\nA\nC\nE\nA\n")),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status", "Position"}))
+		if err != nil {
+			t.Error(err)
+		}
+
+		if diff := cmp.Diff([]string{"D", "A", "C", "E"}, playlistVideoIds(f, "PL1")); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+		want := "B,deleted,1\nB,deleted,4\nD,moved,0\nE,added,3\n"
+		if diff := cmp.Diff(want, w.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if got := f.GetCost(); got != 4*50 {
+			t.Errorf("want cost 200, got: %d", got)
+		}
+	})
+
+	t.Run("inserts in the middle without moves", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf("A", "C", "E")}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		err := s.SyncPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("X\nA\nB\nC\nD\nE\nF\n")),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status", "Position"}))
+		if err != nil {
+			t.Error(err)
+		}
+
+		if diff := cmp.Diff([]string{"X", "A", "B", "C", "D", "E", "F"}, playlistVideoIds(f, "PL1")); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+		want := "X,added,0\nB,added,2\nD,added,4\nF,added,6\n"
+		if diff := cmp.Diff(want, w.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if len(f.plupdate) != 0 {
+			t.Errorf("want no updates, got: %s", f.plupdate)
+		}
+		if got := f.GetCost(); got != 4*50 {
+			t.Errorf("want cost 200, got: %d", got)
+		}
+	})
+
+	t.Run("reorders with the minimum number of moves", func(t *testing.T) {
+		videos := []string{}
+		for i := 0; i < 30; i++ {
+			videos = append(videos, fmt.Sprintf("V%02d", i))
+		}
+		for seed := int64(0); seed < 20; seed++ {
+			desired := append([]string{}, videos...)
+			rand.New(rand.NewSource(seed)).Shuffle(len(desired), func(i, j int) {
+				desired[i], desired[j] = desired[j], desired[i]
+			})
+			f := newFakeService()
+			f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf(videos...)}
+			s := youtubetoolkit.NewWithService(f)
+
+			err := s.SyncPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(strings.Join(desired, "\n"))),
+				youtubetoolkit.NullSink())
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(desired, playlistVideoIds(f, "PL1")); diff != "" {
+				t.Errorf("seed %d: playlist mismatch (-want +got):\n%s", seed, diff)
+			}
+			// the videos in the longest increasing subsequence stay still
+			if got, max := f.GetCost()/bigg.QUOTA_COST_UPDATE, uint32(len(videos)-lisLength(desired)); got != max {
+				t.Errorf("seed %d: want %d moves, got: %d", seed, max, got)
+			}
+		}
+	})
+
+	t.Run("moves only the videos already present", func(t *testing.T) {
+		videos, present := []string{}, []string{}
+		for i := 0; i < 30; i++ {
+			videos = append(videos, fmt.Sprintf("V%02d", i))
+			if i%2 == 0 {
+				present = append(present, videos[i])
+			}
+		}
+		for seed := int64(0); seed < 20; seed++ {
+			desired := append([]string{}, videos...)
+			rand.New(rand.NewSource(seed)).Shuffle(len(desired), func(i, j int) {
+				desired[i], desired[j] = desired[j], desired[i]
+			})
+			kept := []string{} // the even videos, in desired order
+			for _, v := range desired {
+				if v[len(v)-1]%2 == 0 {
+					kept = append(kept, v)
+				}
+			}
+			f := newFakeService()
+			f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf(present...)}
+			s := youtubetoolkit.NewWithService(f)
+
+			err := s.SyncPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(strings.Join(desired, "\n"))),
+				youtubetoolkit.NullSink())
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(desired, playlistVideoIds(f, "PL1")); diff != "" {
+				t.Errorf("seed %d: playlist mismatch (-want +got):\n%s", seed, diff)
+			}
+			if got, max := len(f.plupdate), len(kept)-lisLength(kept); got != max {
+				t.Errorf("seed %d: want %d moves, got: %d", seed, max, got)
+			}
+		}
+	})
+
+	t.Run("refuses a plan exceeding the budget", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf("A", "B")}
+		s := youtubetoolkit.NewWithService(f)
+		s.SetQuotaBudget(100, youtubetoolkit.BudgetTruncate)
+
+		err := s.SyncPlaylist("PL1", youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("C\nD\nB\n")),
+			youtubetoolkit.NullSink())
+		if !errors.Is(err, youtubetoolkit.ErrQuotaBudgetExceeded) {
+			t.Errorf("want ErrQuotaBudgetExceeded, got: %v", err)
+		}
+		if diff := cmp.Diff([]string{"A", "B"}, playlistVideoIds(f, "PL1")); diff != "" {
+			t.Errorf("playlist changed (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSortPlaylist(t *testing.T) {
+	t.Run("moves only the videos out of order", func(t *testing.T) {
+		f := newFakeService()
+		// built by lastuploads: newest first, with a duplicate
+		items := playlistOf("C", "B", "D", "A", "B")
+		for i, date := range []string{"2024-03", "2024-02", "2024-04", "2024-01", "2024-02"} {
+			items[i].ContentDetails = &youtube.PlaylistItemContentDetails{VideoPublishedAt: date}
+		}
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": items}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		keys, _ := youtubetoolkit.ParseSortKeys("VideoPublishedAt", youtubetoolkit.PlaylistVideoItem)
+		err := s.SortPlaylist("PL1", keys, youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Status", "Position"}))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]string{"A", "B", "B", "C", "D"}, playlistVideoIds(f, "PL1")); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+		// A and the second B stay still: 3 moves, the minimum
+		want := "B,moved,3\nC,moved,4\nD,moved,4\n"
+		if diff := cmp.Diff(want, w.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if got := f.GetCost(); got != 3*bigg.QUOTA_COST_UPDATE {
+			t.Errorf("want cost 150, got: %d", got)
+		}
+	})
+
+	t.Run("by duration with the video details", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL1": playlistOf("A", "B", "C")}
+		f.videos = map[string]bigg.Video{
+			"A": newVideo("A", "PT9M", 1, "none"),
+			"B": newVideo("B", "PT1H", 1, "none"),
+			"C": newVideo("C", "PT10M", 1, "none"),
+		}
+		s := youtubetoolkit.NewWithService(f)
+
+		keys, _ := youtubetoolkit.ParseSortKeys("Duration:desc", youtubetoolkit.PlaylistVideoItem)
+		err := s.SortPlaylist("PL1", keys, youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		// as seconds, not as the ISO 8601 strings
+		if diff := cmp.Diff([]string{"B", "C", "A"}, playlistVideoIds(f, "PL1")); diff != "" {
+			t.Errorf("playlist mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSubscriptionsDiff(t *testing.T) {
+	t.Run("channels only in A, only in B and in common", func(t *testing.T) {
+		fa := newFakeService()
+		fa.subslist = []bigg.Sub{newSub("A", "TA"), newSub("B", "TB"), newSub("C", "TC")}
+		fb := newFakeService()
+		fb.subslist = []bigg.Sub{newSub("B", "TB"), newSub("D", "TD")}
+		a := youtubetoolkit.NewWithService(fa)
+		b := youtubetoolkit.NewWithService(fb)
+		w := &bytes.Buffer{}
+
+		err := a.SubscriptionsDiff(b, youtubetoolkit.CSVSink(w, &[]string{"ChannelId", "Status"}))
+		if err != nil {
+			t.Error(err)
+		}
+		want := "A,only-a\nC,only-a\nD,only-b\nB,common\n"
+		if diff := cmp.Diff(want, w.String()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSyncSubscriptions(t *testing.T) {
+	setup := func() (*fakeService, *youtubetoolkit.Toolkit, *youtubetoolkit.Toolkit) {
+		fa := newFakeService()
+		fa.subslist = []bigg.Sub{newSub("A", "TA"), newSub("B", "TB"), newSub("C", "TC")}
+		fb := newFakeService()
+		fb.subslist = []bigg.Sub{newSub("B", "TB"), newSub("D", "TD"), newSub("E", "TE")}
+		return fb, youtubetoolkit.NewWithService(fa), youtubetoolkit.NewWithService(fb)
+	}
+
+	t.Run("only adds without prune", func(t *testing.T) {
+		fb, a, b := setup()
+		err := b.SyncSubscriptions(a, youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		sort.Strings(fb.subinsert)
+		if diff := cmp.Diff([]string{"A", "C"}, fb.subinsert); diff != "" {
+			t.Errorf("inserts mismatch (-want +got):\n%s", diff)
+		}
+		if len(fb.subdelete) != 0 {
+			t.Errorf("want no deletes, got: %s", fb.subdelete)
+		}
+	})
+
+	t.Run("mirrors A with prune", func(t *testing.T) {
+		fb, a, b := setup()
+		w := &bytes.Buffer{}
+		err := b.SyncSubscriptions(a, youtubetoolkit.Prune(),
+			youtubetoolkit.CSVSink(w, &[]string{"ChannelId", "Status"}))
+		if err != nil {
+			t.Error(err)
+		}
+		sort.Strings(fb.subdelete)
+		if diff := cmp.Diff([]string{"D", "E"}, fb.subdelete); diff != "" {
+			t.Errorf("deletes mismatch (-want +got):\n%s", diff)
+		}
+		got := strings.Split(strings.TrimSpace(w.String()), "\n")
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"A,added", "C,added", "D,deleted", "E,deleted"}, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		// deleted by subscription ID, without looking it up
+		if got := fb.GetCost(); got != 4*50 {
+			t.Errorf("want cost 200, got: %d", got)
+		}
+	})
+
+	t.Run("refuses a plan exceeding the budget", func(t *testing.T) {
+		fb, a, b := setup()
+		b.SetQuotaBudget(150, youtubetoolkit.BudgetRefuse)
+		err := b.SyncSubscriptions(a, youtubetoolkit.Prune(), youtubetoolkit.NullSink())
+		if !errors.Is(err, youtubetoolkit.ErrQuotaBudgetExceeded) {
+			t.Errorf("want ErrQuotaBudgetExceeded, got: %v", err)
+		}
+		if len(fb.subinsert)+len(fb.subdelete) != 0 {
+			t.Errorf("want no changes, got inserts %s and deletes %s", fb.subinsert, fb.subdelete)
+		}
+	})
+}
+
+func TestOPML(t *testing.T) {
+	t.Run("subscriptions round trip", func(t *testing.T) {
+		f := newFakeService()
+		f.subslist = []bigg.Sub{newSub("A", "TA"), newSub("B", "T&B")}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		err := s.Subscriptions(youtubetoolkit.OPMLSink(w))
+		if err != nil {
+			t.Error(err)
+		}
+		if !strings.Contains(w.String(), `xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=A"`) {
+			t.Errorf("feed url not found in:\n%s", w.String())
+		}
+
+		err = s.Subscribe(youtubetoolkit.OPMLSource(w), youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]string{"A", "B"}, f.subinsert); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("reads nested outlines and ignores other feeds", func(t *testing.T) {
+		in := `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="News" title="News">
+    <outline type="rss" text="C1" xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=UC1"/>
+    <outline type="rss" text="blog" xmlUrl="https://example.com/rss"/>
+    <outline type="rss" text="proxy" xmlUrl="https://rss.example.com/feeds/videos.xml?channel_id=JUNK1"/>
+    <outline type="rss" text="forum" xmlUrl="https://forum.example.org/feed.php?channel_id=JUNK2"/>
+  </outline>
+  <outline type="rss" text="C2" xmlUrl="https://www.youtube.com/feeds/videos.xml?channel_id=UC2"/>
+  <outline type="rss" text="C3" xmlUrl="https://youtube.com/feeds/videos.xml?channel_id=UC3"/>
+</body></opml>`
+		f := newFakeService()
+		s := youtubetoolkit.NewWithService(f)
+		err := s.Subscribe(youtubetoolkit.OPMLSource(strings.NewReader(in)), youtubetoolkit.NullSink())
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff([]string{"UC1", "UC2", "UC3"}, f.subinsert); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSubscriptionsFormats(t *testing.T) {
+	formats := []struct {
+		name   string
+		sink   func(io.Writer) youtubetoolkit.FlowOption
+		source func(io.Reader) youtubetoolkit.FlowOption
+		sample string
+	}{
+		{"takeout", youtubetoolkit.TakeoutSink, youtubetoolkit.TakeoutSource,
+			"Channel Id,Channel Url,Channel Title\n" +
+				"UC1,http://www.youtube.com/channel/UC1,First\n" +
+				"UC2,http://www.youtube.com/channel/UC2,\"Second, with comma\"\n\n"},
+		{"newpipe", youtubetoolkit.NewPipeSink, youtubetoolkit.NewPipeSource,
+			`{"app_version":"0.24.1","app_version_int":990,"subscriptions":[` +
+				`{"service_id":0,"url":"https://www.youtube.com/channel/UC1","name":"First"},` +
+				`{"service_id":1,"url":"https://soundcloud.com/someone","name":"Other service"},` +
+				`{"service_id":0,"url":"https://www.youtube.com/channel/UC2","name":"Second"}]}`},
+		{"freetube", youtubetoolkit.FreeTubeSink, youtubetoolkit.FreeTubeSource,
+			`{"name":"All Channels","bgColor":"#000000","textColor":"#FFFFFF","subscriptions":[{"id":"UC1","name":"First","thumbnail":""},{"id":"UC2","name":"Second","thumbnail":""}],"_id":"allChannels"}` + "\n" +
+				`{"name":"Music","bgColor":"#FF0000","textColor":"#FFFFFF","subscriptions":[{"id":"UC2","name":"Second","thumbnail":""}],"_id":"music"}` + "\n"},
+		{"invidious", youtubetoolkit.InvidiousSink, youtubetoolkit.InvidiousSource,
+			`{"subscriptions":["UC1","UC2"],"watch_history":["dQw4w9WgXcQ"],"preferences":{"locale":"en-US"},"playlists":[]}`},
+	}
+	for _, tt := range formats {
+		t.Run(tt.name+" sample", func(t *testing.T) {
+			f := newFakeService()
+			s := youtubetoolkit.NewWithService(f)
+			err := s.Subscribe(tt.source(strings.NewReader(tt.sample)), youtubetoolkit.NullSink())
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff([]string{"UC1", "UC2"}, f.subinsert); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+		t.Run(tt.name+" round trip", func(t *testing.T) {
+			f := newFakeService()
+			f.subslist = []bigg.Sub{newSub("A", "TA"), newSub("B", "T,B")}
+			s := youtubetoolkit.NewWithService(f)
+			w := &bytes.Buffer{}
+			if err := s.Subscriptions(tt.sink(w)); err != nil {
+				t.Error(err)
+			}
+			if err := s.Subscribe(tt.source(w), youtubetoolkit.NullSink()); err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff([]string{"A", "B"}, f.subinsert); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCachedService(t *testing.T) {
+	setup := func() *fakeService {
+		f := newFakeService()
+		f.channels = map[string]bigg.Channel{"CH1": newChannel("PL1"), "CH2": newChannel("PL2")}
+		f.playlistitems = map[string][]bigg.PlaylistItem{
+			"PL1": {newPlaylistItem("VIDEO1", "T1", "", "", time.Now().Add(-time.Hour).Format(bigg.ISO8601_LAYOUT))},
+			"PL2": {newPlaylistItem("VIDEO2", "T2", "", "", time.Now().Add(-time.Hour).Format(bigg.ISO8601_LAYOUT))},
+		}
+		return f
+	}
+	lastUploads := func(s *youtubetoolkit.Toolkit) string {
+		w := &bytes.Buffer{}
+		err := s.LastUploads(time.Now().Add(-2*time.Hour),
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("CH1\nCH2\n")),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))
+		if err != nil {
+			t.Error(err)
+		}
+		return w.String()
+	}
+
+	t.Run("channels are fetched once across runs", func(t *testing.T) {
+		f := setup()
+		file := filepath.Join(t.TempDir(), "cache.json")
+		for run := 0; run < 3; run++ {
+			cache, err := youtubetoolkit.LoadCache(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs := youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.DefaultCacheTTL)
+			out := lastUploads(youtubetoolkit.NewWithService(cs))
+			if out != "VIDEO1\nVIDEO2\n" && out != "VIDEO2\nVIDEO1\n" {
+				t.Errorf("run %d: unexpected output %q", run, out)
+			}
+			if err := cache.Save(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := f.channelInfoCalls(); got != 2 {
+			t.Errorf("want 2 channel lookups, got: %d", got)
+		}
+	})
+
+	t.Run("expired entries are fetched again", func(t *testing.T) {
+		f := setup()
+		cache, err := youtubetoolkit.LoadCache(filepath.Join(t.TempDir(), "cache.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs := youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.CacheTTL{Channels: time.Nanosecond})
+		lastUploads(youtubetoolkit.NewWithService(cs))
+		time.Sleep(time.Millisecond)
+		lastUploads(youtubetoolkit.NewWithService(cs))
+		if got := f.channelInfoCalls(); got != 4 {
+			t.Errorf("want 4 channel lookups, got: %d", got)
+		}
+		if hits, misses := cs.Counts(); hits != 0 || misses != 4 {
+			t.Errorf("want 0 hits and 4 misses, got: %d and %d", hits, misses)
+		}
+	})
+
+	t.Run("videos not found are cached too", func(t *testing.T) {
+		f := newFakeService()
+		f.videos = map[string]bigg.Video{"V1": newVideo("V1", "PT1M", 10, "none")}
+		file := filepath.Join(t.TempDir(), "cache.json")
+		for run := 0; run < 2; run++ {
+			cache, err := youtubetoolkit.LoadCache(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs := youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.DefaultCacheTTL)
+			vs, err := cs.VideosList(context.Background(), []string{"V1", "DELETED"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vs) != 1 || vs[0].Id != "V1" {
+				t.Errorf("run %d: want only V1, got: %v", run, vs)
+			}
+			if err := cache.Save(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if diff := cmp.Diff([]int{2}, f.videobatches); diff != "" {
+			t.Errorf("batches mismatch (-want +got):\n%s", diff)
+		}
+
+		// and looked up again once expired
+		cache, err := youtubetoolkit.LoadCache(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs := youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.CacheTTL{Videos: time.Nanosecond})
+		time.Sleep(time.Millisecond)
+		if _, err := cs.VideosList(context.Background(), []string{"DELETED"}); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]int{2, 1}, f.videobatches); diff != "" {
+			t.Errorf("batches mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("stats and clear", func(t *testing.T) {
+		f := setup()
+		file := filepath.Join(t.TempDir(), "cache.json")
+		cache, err := youtubetoolkit.LoadCache(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lastUploads(youtubetoolkit.NewWithService(youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.DefaultCacheTTL)))
+		if err := cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+		stats := cache.Stats()
+		if len(stats) != 1 || stats[0].Kind != youtubetoolkit.CacheChannels || stats[0].Entries != 2 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		if err := cache.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("want cache file removed, got: %v", err)
+		}
+	})
+}
+
+func TestPlaylistMetadata(t *testing.T) {
+	f := newFakeService()
+	cache, err := youtubetoolkit.LoadCache(filepath.Join(t.TempDir(), "cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := youtubetoolkit.NewCachedService(f, cache, youtubetoolkit.DefaultCacheTTL)
+	s := youtubetoolkit.NewWithService(cs)
+
+	if _, err := s.NewPlaylistWithMetadata(bigg.PlaylistMetadata{Title: "T", PrivacyStatus: "secret"}); err == nil {
+		t.Error("want an invalid privacy error")
+	}
+	id, err := s.NewPlaylistWithMetadata(bigg.PlaylistMetadata{Title: "T", Description: "D", PrivacyStatus: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cached before the update
+	if _, err := cs.PlaylistInfo(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.UpdatePlaylist(id, func(m *bigg.PlaylistMetadata) {
+		m.Title = "T2"
+		m.PrivacyStatus = "public"
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	w := &bytes.Buffer{}
+	err = s.Playlists(youtubetoolkit.CSVSink(w, &[]string{"PlaylistTitle", "Description", "PrivacyStatus"}))
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff("T2,D,public\n", w.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	pl, err := cs.PlaylistInfo(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pl.Snippet.Title != "T2" {
+		t.Errorf("want the updated playlist, got the cached one: %s", pl.Snippet.Title)
+	}
+
+	err = s.UpdatePlaylist(id, func(m *bigg.PlaylistMetadata) { m.Title = "" })
+	if err == nil {
+		t.Error("want a missing title error")
+	}
+
+	// title only
+	id, err = s.NewPlaylist("T3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pl := f.playlists[len(f.playlists)-1]; pl.Id != id || pl.Snippet.Title != "T3" || pl.Snippet.Description != "" {
+		t.Errorf("want a playlist with only the title T3, got: %s %s", pl.Id, pl.Snippet.Title)
+	}
+}
+
+func TestVideoDetails(t *testing.T) {
+	t.Run("joins the details of the playlist videos, 50 at a time", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{}
+		f.videos = map[string]bigg.Video{}
+		want := &strings.Builder{}
+		for i := 0; i < 60; i++ {
+			id := fmt.Sprintf("VIDEO%d", i)
+			f.playlistitems["PL"] = append(f.playlistitems["PL"], newPlaylistItem(id, "T", "", "", ""))
+			if i == 42 {
+				// private or deleted
+				fmt.Fprintf(want, "%s,,0,0,\n", id)
+				continue
+			}
+			f.videos[id] = newVideo(id, "PT1H2M3S", uint64(i*100), "none")
+			fmt.Fprintf(want, "%s,PT1H2M3S,3723,%d,none\n", id, i*100)
+		}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		err := s.Playlist("PL", youtubetoolkit.VideoDetails(),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId", "Duration", "DurationSeconds", "ViewCount", "LiveBroadcastContent"}))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(want.String(), w.String()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]int{50, 10}, f.videobatches); diff != "" {
+			t.Errorf("batches mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("no lookups without the option", func(t *testing.T) {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL": playlistOf("V1", "V2")}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		err := s.Playlist("PL", youtubetoolkit.JSONLinesSink(w))
+		if err != nil {
+			t.Error(err)
+		}
+		if len(f.videobatches) != 0 || strings.Contains(w.String(), "Duration") {
+			t.Errorf("unexpected details lookup: %v %s", f.videobatches, w.String())
+		}
+	})
+}
+
+func TestWhere(t *testing.T) {
+	blocked := filepath.Join(t.TempDir(), "blocked.txt")
+	if err := os.WriteFile(blocked, []byte("CH2\nCH3,a blocked channel\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := newFakeService()
+	f.playlistitems = map[string][]bigg.PlaylistItem{"PL": {
+		newPlaylistItem("V1", "The Podcast #1", "CH1", "", "2023-12-31T10:00:00Z"),
+		newPlaylistItem("V2", "a PODCAST", "CH2", "", "2024-02-01T10:00:00Z"),
+		newPlaylistItem("V3", "Other", "CH1", "", "2024-03-01T10:00:00Z"),
+		newPlaylistItem("V4", "podcast", "CH3", "", "2024-04-01T10:00:00Z"),
+	}}
+	f.playlists = []bigg.Playlist{newPlaylist("aaa", "AAA", 3), newPlaylist("bbb", "BBB", 20), newPlaylist("ccc", "CCC", 100)}
+	s := youtubetoolkit.NewWithService(f)
+
+	for _, tc := range []struct {
+		where string
+		want  string
+	}{
+		{`VideoTitle ~ "(?i)podcast"`, "V1\nV2\nV4\n"},
+		{`VideoTitle ~ "(?i)podcast" and not ChannelId in file:` + blocked, "V1\n"},
+		{`ChannelId not in CH1, CH3`, "V2\n"},
+		{`PublishedAt > 2024-01-01 and (VideoId = V2 or VideoTitle != Other)`, "V2\nV4\n"},
+		{`VideoTitle !~ podcast or VideoId == V1`, "V1\nV2\nV3\n"},
+	} {
+		w := &bytes.Buffer{}
+		expr, err := youtubetoolkit.ParseExpr(tc.where, youtubetoolkit.PlaylistVideoItem)
+		if err != nil {
+			t.Errorf("%s: %v", tc.where, err)
+			continue
+		}
+		// the filter applies whatever the options order
+		err = s.Playlist("PL", youtubetoolkit.Where(expr), youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(tc.want, w.String()); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tc.where, diff)
+		}
+	}
+
+	t.Run("numeric fields compare as numbers", func(t *testing.T) {
+		expr, err := youtubetoolkit.ParseExpr("VideoCount >= 20", youtubetoolkit.PlaylistItem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := &bytes.Buffer{}
+		err = s.Playlists(youtubetoolkit.JSONLinesSink(w), youtubetoolkit.Where(expr))
+		if err != nil {
+			t.Error(err)
+		}
+		if got := strings.Count(w.String(), "\n"); got != 2 || strings.Contains(w.String(), "aaa") {
+			t.Errorf("want bbb and ccc, got: %s", w.String())
+		}
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		for _, where := range []string{
+			`Title ~ podcast`,
+			`VideoTitle ~ "(unclosed"`,
+			`ChannelId in file:` + filepath.Join(t.TempDir(), "missing.txt"),
+			`VideoId = `,
+			`VideoId V1`,
+			`(VideoId = V1`,
+			`VideoId = V1 V2`,
+			`VideoId => V1`,
+			`VideoTitle = "unterminated`,
+		} {
+			if _, err := youtubetoolkit.ParseExpr(where, youtubetoolkit.PlaylistVideoItem); err == nil {
+				t.Errorf("%s: want an error", where)
+			}
+		}
+		_, err := youtubetoolkit.ParseExpr("Title ~ podcast", youtubetoolkit.PlaylistVideoItem)
+		if err == nil || !strings.Contains(err.Error(), `unknown field "Title"`) {
+			t.Errorf("want an unknown field error, got: %v", err)
+		}
+	})
+}
+
+func TestItemTypeFields(t *testing.T) {
+	f := newFakeService()
+	f.subslist = []bigg.Sub{newSub("CH1", "Podcasts"), newSub("CH2", "Other")}
+	f.playlists = []bigg.Playlist{newPlaylist("aaa", "Podcasts", 3), newPlaylist("bbb", "Other", 20)}
+	f.playlistitems = map[string][]bigg.PlaylistItem{"PL": {
+		newPlaylistItem("V1", "Podcast", "CH1", "", ""),
+		newPlaylistItem("V2", "Other", "CH1", "", ""),
+	}}
+	s := youtubetoolkit.NewWithService(f)
+
+	for _, tc := range []struct {
+		name     string
+		itemType youtubetoolkit.ItemType
+		where    string
+		sortBy   string
+		foreign  []string // fields of the other Items
+		list     func(opts ...youtubetoolkit.FlowOption) error
+		fields   []string
+		want     string
+	}{
+		{"subscriptions", youtubetoolkit.SubscriptionItem, `ChannelTitle ~ Podcast`, "ChannelTitle:desc",
+			[]string{"VideoTitle", "PlaylistTitle", "VideoCount"},
+			s.Subscriptions, []string{"ChannelId"}, "CH1\n"},
+		{"playlists", youtubetoolkit.PlaylistItem, `PlaylistTitle ~ Podcast`, "VideoCount",
+			[]string{"VideoTitle", "ChannelTitle", "SubscriptionId"},
+			s.Playlists, []string{"PlaylistId"}, "aaa\n"},
+		{"playlist", youtubetoolkit.PlaylistVideoItem, `VideoTitle ~ Podcast`, "ViewCount:desc",
+			[]string{"PlaylistTitle", "VideoCount", "SubscriptionId"},
+			func(opts ...youtubetoolkit.FlowOption) error { return s.Playlist("PL", opts...) }, []string{"VideoId"}, "V1\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := youtubetoolkit.ParseExpr(tc.where, tc.itemType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := youtubetoolkit.ParseSortKeys(tc.sortBy, tc.itemType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := &bytes.Buffer{}
+			err = tc.list(youtubetoolkit.CSVSink(w, &tc.fields), youtubetoolkit.Where(expr), youtubetoolkit.SortBy(keys...))
+			if err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff(tc.want, w.String()); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+
+			for _, field := range tc.foreign {
+				_, err := youtubetoolkit.ParseExpr(field+` ~ "x"`, tc.itemType)
+				if err == nil || !strings.Contains(err.Error(), "unknown field") {
+					t.Errorf("where %s: want an unknown field error, got: %v", field, err)
+				}
+				_, err = youtubetoolkit.ParseSortKeys(field, tc.itemType)
+				if err == nil || !strings.Contains(err.Error(), "unknown field") {
+					t.Errorf("sort by %s: want an unknown field error, got: %v", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSortByLimit(t *testing.T) {
+	f := newFakeService()
+	f.playlists = []bigg.Playlist{newPlaylist("aaa", "BBB", 3), newPlaylist("bbb", "AAA", 20),
+		newPlaylist("ccc", "CCC", 100), newPlaylist("ddd", "AAA", 5)}
+	s := youtubetoolkit.NewWithService(f)
+
+	for _, tc := range []struct {
+		sortBy string
+		limit  int
+		want   string
+	}{
+		{"VideoCount:desc", 0, "ccc\nbbb\nddd\naaa\n"}, // as numbers: 100 > 20 > 5 > 3
+		{"PlaylistTitle,VideoCount:desc", 0, "bbb\nddd\naaa\nccc\n"},
+		{"PlaylistTitle:asc,VideoCount", 3, "ddd\nbbb\naaa\n"},
+		{"", 2, "aaa\nbbb\n"},
+	} {
+		w := &bytes.Buffer{}
+		keys, err := youtubetoolkit.ParseSortKeys(tc.sortBy, youtubetoolkit.PlaylistItem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// sort and limit apply whatever the options order
+		err = s.Playlists(youtubetoolkit.Limit(tc.limit), youtubetoolkit.CSVSink(w, &[]string{"PlaylistId"}), youtubetoolkit.SortBy(keys...))
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff(tc.want, w.String()); diff != "" {
+			t.Errorf("%s limit %d: mismatch (-want +got):\n%s", tc.sortBy, tc.limit, diff)
+		}
+	}
+
+	t.Run("limit stops the listing", func(t *testing.T) {
+		f := newFakeService()
+		items := []bigg.PlaylistItem{}
+		for i := 0; i < 1000; i++ {
+			items = append(items, newPlaylistItem(fmt.Sprintf("V%d", i), "video", "CH1", "", "2024-01-01T10:00:00Z"))
+		}
+		f.playlistitems = map[string][]bigg.PlaylistItem{"PL": items}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+		err := s.Playlist("PL", youtubetoolkit.CSVSink(w, &[]string{"VideoId"}), youtubetoolkit.Limit(3))
+		if err != nil {
+			t.Errorf("want no error, got: %v", err)
+		}
+		if diff := cmp.Diff("V0\nV1\nV2\n", w.String()); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		if sent := atomic.LoadInt32(&f.plsent); sent >= 100 {
+			t.Errorf("want the listing stopped, got %d items listed", sent)
+		}
+	})
+
+	t.Run("limit loads only the pages needed", func(t *testing.T) {
+		items := []bigg.PlaylistItem{}
+		for i := 0; i < 120; i++ {
+			items = append(items, newPlaylistItem(fmt.Sprintf("V%d", i), "video", "CH1", "", "2024-01-01T10:00:00Z"))
+		}
+		for _, tc := range []struct {
+			limit int
+			pages int32
+		}{
+			{0, 3},
+			{10, 1},
+			{50, 1},
+			{60, 2},
+			{100, 2},
+		} {
+			f := newFakeService()
+			f.playlistitems = map[string][]bigg.PlaylistItem{"PL": items}
+			s := youtubetoolkit.NewWithService(f)
+			w := &bytes.Buffer{}
+			err := s.Playlist("PL", youtubetoolkit.CSVSink(w, &[]string{"VideoId"}), youtubetoolkit.Limit(tc.limit))
+			if err != nil {
+				t.Errorf("limit %d: want no error, got: %v", tc.limit, err)
+			}
+			want := tc.limit
+			if want == 0 {
+				want = len(items)
+			}
+			if got := strings.Count(w.String(), "\n"); got != want {
+				t.Errorf("limit %d: want %d videos, got: %d", tc.limit, want, got)
+			}
+			if got := atomic.LoadInt32(&f.plpages); got != tc.pages {
+				t.Errorf("limit %d: want %d pages, got: %d", tc.limit, tc.pages, got)
+			}
+		}
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		for _, sortBy := range []string{"Title", "PlaylistTitle:up"} {
+			if _, err := youtubetoolkit.ParseSortKeys(sortBy, youtubetoolkit.PlaylistItem); err == nil {
+				t.Errorf("%s: want an error", sortBy)
+			}
+		}
+	})
+}
+
 func TestCSVPlaylists(t *testing.T) {
 	t.Run("write a csv with 2 playlists", func(t *testing.T) {
 		f := newFakeService()
@@ -142,6 +1424,219 @@ func TestCSVLastUploads(t *testing.T) {
 	})
 }
 
+func TestLastUploadsUploadsPlaylist(t *testing.T) {
+	t.Run("derives the uploads playlist of UC channel IDs", func(t *testing.T) {
+		published := time.Now().Add(-time.Hour).Format(bigg.ISO8601_LAYOUT)
+		f := newFakeService()
+		f.channels = map[string]bigg.Channel{
+			"legacy":                   newChannel("PL-LEGACY"),
+			"UCmissinguploadsplaylist": newChannel("PL-OTHER"),
+		}
+		f.playlistitems = map[string][]bigg.PlaylistItem{
+			"UU0123456789012345678901": {newPlaylistItem("VIDEO1", "T1", "", "", published)},
+			"UUabcdefghijklmnopqrstuv": {newPlaylistItem("VIDEO2", "T2", "", "", published)},
+			"PL-LEGACY":                {newPlaylistItem("VIDEO3", "T3", "", "", published)},
+			"PL-OTHER":                 {newPlaylistItem("VIDEO4", "T4", "", "", published)},
+		}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		in := "UC0123456789012345678901\nUCabcdefghijklmnopqrstuv\nlegacy\nUCmissinguploadsplaylist\n"
+		err := s.LastUploads(time.Now().Add(-2*time.Hour),
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in)),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))
+		if err != nil {
+			t.Error(err)
+		}
+		got := strings.Split(strings.TrimSpace(w.String()), "\n")
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"VIDEO1", "VIDEO2", "VIDEO3", "VIDEO4"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		// only the legacy ID and the UC ID without a UU playlist are looked up
+		if got := f.channelInfoCalls(); got != 2 {
+			t.Errorf("want 2 channel lookups, got: %d", got)
+		}
+	})
+}
+
+func TestLastUploadsBatchedLookups(t *testing.T) {
+	t.Run("looks up channels 50 at a time and reports the missing ones", func(t *testing.T) {
+		published := time.Now().Add(-time.Hour).Format(bigg.ISO8601_LAYOUT)
+		f := newFakeService()
+		f.channels = map[string]bigg.Channel{}
+		f.playlistitems = map[string][]bigg.PlaylistItem{}
+		in := &strings.Builder{}
+		for i := 0; i < 120; i++ {
+			ch := fmt.Sprintf("CH%d", i)
+			in.WriteString(ch + "\n")
+			if i%40 == 39 {
+				continue // missing
+			}
+			pl := fmt.Sprintf("PL%d", i)
+			f.channels[ch] = newChannel(pl)
+			f.playlistitems[pl] = []bigg.PlaylistItem{newPlaylistItem(fmt.Sprintf("V%d", i), "T", "", "", published)}
+		}
+		s := youtubetoolkit.NewWithService(f)
+		w := &bytes.Buffer{}
+
+		err := s.LastUploads(time.Now().Add(-2*time.Hour),
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in.String())),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))
+
+		var merr youtubetoolkit.MultiErrors
+		if !errors.As(err, &merr) || len(merr) != 3 {
+			t.Fatalf("want 3 errors, got: %v", err)
+		}
+		for _, id := range []string{"CH39", "CH79", "CH119"} {
+			if !strings.Contains(err.Error(), "channel not found for id="+id) {
+				t.Errorf("want a not found error for %s, got: %v", id, err)
+			}
+		}
+		if got := strings.Count(w.String(), "\n"); got != 117 {
+			t.Errorf("want 117 videos, got: %d", got)
+		}
+		if diff := cmp.Diff([]int{50, 50, 20}, f.channelbatches); diff != "" {
+			t.Errorf("batches mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestLastUploadsExclude(t *testing.T) {
+	published := time.Now().Add(-time.Hour).Format(bigg.ISO8601_LAYOUT)
+	setup := func() *fakeService {
+		f := newFakeService()
+		f.playlistitems = map[string][]bigg.PlaylistItem{
+			"UULF0123456789012345678901": {
+				newPlaylistItem("LONG", "T", "", "", published),
+				newPlaylistItem("PREMIERE", "T", "", "", published),
+				newPlaylistItem("UPCOMING", "T", "", "", published),
+			},
+			"UUSH0123456789012345678901": {newPlaylistItem("SHORT", "T", "", "", published)},
+			"UULV0123456789012345678901": {newPlaylistItem("LIVE", "T", "", "", published)},
+			// no live streams
+			"UULFabcdefghijklmnopqrstuv": {newPlaylistItem("LONG2", "T", "", "", published)},
+		}
+		for _, id := range []string{"0123456789012345678901", "abcdefghijklmnopqrstuv"} {
+			for _, variant := range []string{"UULF", "UUSH", "UULV"} {
+				f.playlistitems["UU"+id] = append(f.playlistitems["UU"+id], f.playlistitems[variant+id]...)
+			}
+		}
+		premiere := newVideo("PREMIERE", "PT10M", 0, "none")
+		premiere.LiveStreamingDetails = &youtube.VideoLiveStreamingDetails{ActualStartTime: published}
+		f.videos = map[string]bigg.Video{
+			"LONG":     newVideo("LONG", "PT20M", 0, "none"),
+			"LONG2":    newVideo("LONG2", "PT3M", 0, "none"),
+			"PREMIERE": premiere,
+			"UPCOMING": newVideo("UPCOMING", "P0D", 0, "upcoming"),
+			"SHORT":    newVideo("SHORT", "PT30S", 0, "none"),
+			"LIVE":     newVideo("LIVE", "PT2H", 0, "none"),
+		}
+		return f
+	}
+	lastUploads := func(f *fakeService, opts ...youtubetoolkit.FlowOption) []string {
+		w := &bytes.Buffer{}
+		s := youtubetoolkit.NewWithService(f)
+		err := s.LastUploads(time.Now().Add(-2*time.Hour), append(opts,
+			youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader("UC0123456789012345678901\nUCabcdefghijklmnopqrstuv\n")),
+			youtubetoolkit.CSVSink(w, &[]string{"VideoId"}))...)
+		if err != nil {
+			t.Error(err)
+		}
+		got := strings.Split(strings.TrimSpace(w.String()), "\n")
+		sort.Strings(got)
+		return got
+	}
+
+	t.Run("shorts and live streams are skipped listing the long-form videos only", func(t *testing.T) {
+		f := setup()
+		got := lastUploads(f, youtubetoolkit.ExcludeVideos(youtubetoolkit.VideoShorts, youtubetoolkit.VideoLive))
+		if diff := cmp.Diff([]string{"LONG", "LONG2", "PREMIERE", "UPCOMING"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		sort.Strings(f.pllisted)
+		if diff := cmp.Diff([]string{"UULF0123456789012345678901", "UULFabcdefghijklmnopqrstuv"}, f.pllisted); diff != "" {
+			t.Errorf("listed playlists mismatch (-want +got):\n%s", diff)
+		}
+		if len(f.videobatches) != 0 {
+			t.Errorf("unexpected video details lookup: %v", f.videobatches)
+		}
+	})
+
+	t.Run("premieres and upcoming videos are skipped by the video details", func(t *testing.T) {
+		f := setup()
+		got := lastUploads(f, youtubetoolkit.ExcludeVideos(youtubetoolkit.VideoPremiere, youtubetoolkit.VideoUpcoming))
+		if diff := cmp.Diff([]string{"LIVE", "LONG", "LONG2", "SHORT"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("duration range", func(t *testing.T) {
+		f := setup()
+		got := lastUploads(f, youtubetoolkit.VideoDuration(time.Minute, time.Hour))
+		if diff := cmp.Diff([]string{"LONG", "LONG2", "PREMIERE"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+		sort.Strings(f.pllisted)
+		if diff := cmp.Diff([]string{"UU0123456789012345678901", "UUabcdefghijklmnopqrstuv"}, f.pllisted); diff != "" {
+			t.Errorf("listed playlists mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unknown kinds", func(t *testing.T) {
+		if _, err := youtubetoolkit.ParseVideoKinds("shorts,reels"); err == nil {
+			t.Error("want an error")
+		}
+	})
+}
+
+func TestLastUploadsCancel(t *testing.T) {
+	t.Run("stops a running flow when the context is canceled", func(t *testing.T) {
+		f := newFakeService()
+		f.channels = map[string]bigg.Channel{}
+		f.playlistitems = map[string][]bigg.PlaylistItem{}
+		in := &strings.Builder{}
+		for i := 0; i < 100; i++ {
+			ch := fmt.Sprintf("CH%d", i)
+			pl := fmt.Sprintf("PL%d", i)
+			f.channels[ch] = newChannel(pl)
+			for j := 0; j < 50; j++ {
+				f.playlistitems[pl] = append(f.playlistitems[pl],
+					newPlaylistItem(fmt.Sprintf("V%d-%d", i, j), "T", "", "", time.Now().Format(bigg.ISO8601_LAYOUT)))
+			}
+			in.WriteString(ch + "\n")
+		}
+		s := youtubetoolkit.NewWithService(f)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// cancels while the flow is fetching the 10th channel
+		f.onChannelInfo = func(n int) {
+			if n == 10 {
+				cancel()
+			}
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- s.LastUploadsContext(ctx, time.Time{},
+				youtubetoolkit.CSVFirstFieldOnlySource(strings.NewReader(in.String())),
+				youtubetoolkit.NullSink())
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("want context.Canceled, got: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("flow did not stop after cancel")
+		}
+		if got := f.channelInfoCalls(); got > 12 {
+			t.Errorf("want at most 12 channel lookups, got: %d", got)
+		}
+	})
+}
+
 //
 // fakes
 //
@@ -153,36 +1648,142 @@ func newFakeService() *fakeService {
 type fakeService struct {
 	subslist      []bigg.Sub
 	subinsert     []string
+	subdelete     []string
 	playlists     []bigg.Playlist
 	playlistitems map[string][]bigg.PlaylistItem
 	channels      map[string]bigg.Channel
+	videos        map[string]bigg.Video
+	plinsert      []string
+	plupdate      []string
+	pllisted      []string
+	plsent        int32
+	plpages       int32
+
+	cost      uint32
+	extracost uint32
+
+	mu             sync.Mutex
+	channelinfo    int
+	channelbatches []int
+	videobatches   []int
+	onChannelInfo  func(n int)
+}
+
+func (s *fakeService) channelInfoCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.channelinfo
 }
 
 // PlaylistDelete implements youtubetoolkit.YoutubeService
-func (*fakeService) PlaylistDelete(playlistId string) error {
+func (*fakeService) PlaylistDelete(ctx context.Context, playlistId string) error {
 	panic("unimplemented")
 }
 
 // SubscriptionDelete implements youtubetoolkit.YoutubeService
-func (*fakeService) SubscriptionDelete(channelId string) error {
-	panic("unimplemented")
+func (s *fakeService) SubscriptionDelete(ctx context.Context, channelId string) error {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_SUBSCRIPTION_DELETE)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subdelete = append(s.subdelete, channelId)
+	return nil
+}
+
+// SubscriptionDeleteById implements youtubetoolkit.YoutubeService
+func (s *fakeService) SubscriptionDeleteById(ctx context.Context, subscriptionId string) error {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_DELETE)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subslist {
+		if sub.Id == subscriptionId {
+			s.subdelete = append(s.subdelete, sub.Snippet.ResourceId.ChannelId)
+			return nil
+		}
+	}
+	return fmt.Errorf("subscription %s not found", subscriptionId)
 }
 
 // GetChannelInfo implements youtubetoolkit.YoutubeService
-func (s *fakeService) GetChannelInfo(id string) (*bigg.Channel, error) {
+func (s *fakeService) GetChannelInfo(ctx context.Context, id string) (*bigg.Channel, error) {
+	s.mu.Lock()
+	s.channelinfo++
+	if s.onChannelInfo != nil {
+		s.onChannelInfo(s.channelinfo)
+	}
+	s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	o := s.channels[id]
 	return &o, nil
 }
 
+// ChannelsList implements youtubetoolkit.YoutubeService
+// Each ID counts as a channel lookup.
+func (s *fakeService) ChannelsList(ctx context.Context, ids []string) ([]*bigg.Channel, error) {
+	s.mu.Lock()
+	s.channelbatches = append(s.channelbatches, len(ids))
+	s.mu.Unlock()
+	out := []*bigg.Channel{}
+	for _, id := range ids {
+		s.mu.Lock()
+		s.channelinfo++
+		if s.onChannelInfo != nil {
+			s.onChannelInfo(s.channelinfo)
+		}
+		s.mu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if o, ok := s.channels[id]; ok {
+			c := *o.Channel
+			c.Id = id
+			out = append(out, &bigg.Channel{Channel: &c})
+		}
+	}
+	return out, nil
+}
+
 // PlaylistItemsListFiltered implements youtubetoolkit.YoutubeService
-func (s *fakeService) PlaylistItemsList(playlistId string, filter func(*bigg.PlaylistItem) (bool, error), out chan<- *bigg.PlaylistItem) error {
-	for _, v := range s.playlistitems[playlistId] {
+func (s *fakeService) PlaylistItemsList(ctx context.Context, playlistId string, filter func(*bigg.PlaylistItem) (bool, error), out chan<- *bigg.PlaylistItem) error {
+	s.mu.Lock()
+	s.pllisted = append(s.pllisted, playlistId)
+	items, ok := s.playlistitems[playlistId]
+	items = append([]bigg.PlaylistItem{}, items...)
+	s.mu.Unlock()
+	if !ok && s.playlistitems != nil {
+		return &googleapi.Error{Code: http.StatusNotFound, Message: "playlistNotFound"}
+	}
+	// pages like bigg, the last one of the items left with a ListLimit
+	left, limited := bigg.ListLimit(ctx)
+	pageSize := func() int {
+		if limited && left < bigg.MAX_PAGE_SIZE {
+			return left
+		}
+		return bigg.MAX_PAGE_SIZE
+	}
+	atomic.AddInt32(&s.plpages, 1)
+	page := pageSize()
+	for _, v := range items {
+		if page == 0 {
+			atomic.AddInt32(&s.plpages, 1)
+			page = pageSize()
+		}
+		page--
 		o := v
 		ok, err := filter(&o)
 		if err != nil {
 			return err
 		} else if ok {
-			out <- &o
+			select {
+			case out <- &o:
+				atomic.AddInt32(&s.plsent, 1)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if left--; limited && left == 0 {
+				return nil
+			}
 		} else {
 			return nil
 		}
@@ -191,17 +1792,131 @@ func (s *fakeService) PlaylistItemsList(playlistId string, filter func(*bigg.Pla
 }
 
 // PlaylistItemsInsert implements youtubetoolkit.YoutubeService
-func (*fakeService) PlaylistItemsInsert(playlistId string, videoId string) (*bigg.PlaylistItem, error) {
-	panic("unimplemented")
+func (s *fakeService) PlaylistItemsInsert(ctx context.Context, playlistId string, videoId string, position int64) (*bigg.PlaylistItem, error) {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_INSERT)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plinsert = append(s.plinsert, videoId)
+	n := newPlaylistItem(videoId, videoId, "", "", "")
+	if s.playlistitems != nil {
+		items := s.playlistitems[playlistId]
+		if position == bigg.POSITION_APPEND || position > int64(len(items)) {
+			position = int64(len(items))
+		}
+		n.Snippet.Position = position
+		s.playlistitems[playlistId] = append(items[:position:position], append([]bigg.PlaylistItem{n}, items[position:]...)...)
+	}
+	return &n, nil
+}
+
+// PlaylistItemsDelete implements youtubetoolkit.YoutubeService
+func (s *fakeService) PlaylistItemsDelete(ctx context.Context, playlistItemId string) error {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_DELETE)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for plid, items := range s.playlistitems {
+		for i := range items {
+			if items[i].Id == playlistItemId {
+				s.playlistitems[plid] = append(items[:i:i], items[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("playlist item %s not found", playlistItemId)
+}
+
+// PlaylistItemsUpdate implements youtubetoolkit.YoutubeService
+func (s *fakeService) PlaylistItemsUpdate(ctx context.Context, playlistItemId, playlistId, videoId string, position int64) (*bigg.PlaylistItem, error) {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_UPDATE)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plupdate = append(s.plupdate, videoId)
+	items := s.playlistitems[playlistId]
+	for i := range items {
+		if items[i].Id == playlistItemId {
+			o := items[i]
+			items = append(items[:i:i], items[i+1:]...)
+			items = append(items[:position:position], append([]bigg.PlaylistItem{o}, items[position:]...)...)
+			s.playlistitems[playlistId] = items
+			o.Snippet.Position = position
+			return &o, nil
+		}
+	}
+	return nil, fmt.Errorf("playlist item %s not found", playlistItemId)
 }
 
 // PlaylistInsert implements youtubetoolkit.YoutubeService
-func (*fakeService) PlaylistInsert(title string) (*bigg.Playlist, error) {
+func (s *fakeService) PlaylistInsert(ctx context.Context, meta bigg.PlaylistMetadata) (*bigg.Playlist, error) {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_INSERT)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pl := newPlaylist(fmt.Sprintf("NEWPL%d", len(s.playlists)), meta.Title, 0)
+	pl.Snippet.Description = meta.Description
+	pl.Status = &youtube.PlaylistStatus{PrivacyStatus: meta.PrivacyStatus}
+	s.playlists = append(s.playlists, pl)
+	if s.playlistitems != nil {
+		s.playlistitems[pl.Id] = []bigg.PlaylistItem{}
+	}
+	o := pl
+	return &o, nil
+}
+
+// PlaylistUpdate implements youtubetoolkit.YoutubeService
+func (s *fakeService) PlaylistUpdate(ctx context.Context, playlistId string, update func(*bigg.PlaylistMetadata) error) (*bigg.Playlist, error) {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_LIST+bigg.QUOTA_COST_UPDATE)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range s.playlists {
+		if v.Id == playlistId {
+			meta := bigg.PlaylistMetadataOf(&v)
+			if err := update(&meta); err != nil {
+				return nil, err
+			}
+			pl := newPlaylist(playlistId, meta.Title, v.ContentDetails.ItemCount)
+			pl.Snippet.Description = meta.Description
+			pl.Snippet.Tags = meta.Tags
+			pl.Snippet.DefaultLanguage = meta.DefaultLanguage
+			pl.Status = &youtube.PlaylistStatus{PrivacyStatus: meta.PrivacyStatus}
+			s.playlists[i] = pl
+			return &pl, nil
+		}
+	}
+	return nil, fmt.Errorf("playlist not found for id=\"%s\"", playlistId)
+}
+
+// PlaylistInfo implements youtubetoolkit.YoutubeService
+func (s *fakeService) PlaylistInfo(ctx context.Context, id string) (*bigg.Playlist, error) {
+	for _, v := range s.playlists {
+		if v.Id == id {
+			o := v
+			return &o, nil
+		}
+	}
+	return nil, fmt.Errorf("playlist not found for id=\"%s\"", id)
+}
+
+// VideoInfo implements youtubetoolkit.YoutubeService
+func (*fakeService) VideoInfo(ctx context.Context, id string) (*bigg.Video, error) {
 	panic("unimplemented")
 }
 
+// VideosList implements youtubetoolkit.YoutubeService
+func (s *fakeService) VideosList(ctx context.Context, ids []string) ([]*bigg.Video, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.videobatches = append(s.videobatches, len(ids))
+	out := []*bigg.Video{}
+	for _, id := range ids {
+		if v, ok := s.videos[id]; ok {
+			o := v
+			out = append(out, &o)
+		}
+	}
+	return out, nil
+}
+
 // PlaylistsList implements youtubetoolkit.YoutubeService
-func (s *fakeService) PlaylistsList(out chan<- *bigg.Playlist) error {
+func (s *fakeService) PlaylistsList(ctx context.Context, out chan<- *bigg.Playlist) error {
 	for _, v := range s.playlists {
 		o := v
 		out <- &o
@@ -209,7 +1924,7 @@ func (s *fakeService) PlaylistsList(out chan<- *bigg.Playlist) error {
 	return nil
 }
 
-func (s *fakeService) SubscriptionsList(out chan<- *bigg.Sub) error {
+func (s *fakeService) SubscriptionsList(ctx context.Context, out chan<- *bigg.Sub) error {
 	for _, v := range s.subslist {
 		o := v
 		out <- &o
@@ -217,7 +1932,13 @@ func (s *fakeService) SubscriptionsList(out chan<- *bigg.Sub) error {
 	return nil
 }
 
-func (s *fakeService) SubscriptionInsert(chanid string) (*bigg.Sub, error) {
+// GetCost implements youtubetoolkit.QuotaCounter
+func (s *fakeService) GetCost() uint32 {
+	return atomic.LoadUint32(&s.cost)
+}
+
+func (s *fakeService) SubscriptionInsert(ctx context.Context, chanid string) (*bigg.Sub, error) {
+	atomic.AddUint32(&s.cost, bigg.QUOTA_COST_INSERT+s.extracost)
 	s.subinsert = append(s.subinsert, chanid)
 	n := newSub(chanid, chanid)
 	return &n, nil
@@ -276,6 +1997,7 @@ func newPlaylist(id, title string, count int64) bigg.Playlist {
 func newPlaylistItem(videoid, title, chaId, chaTitle, publishedAt string) bigg.PlaylistItem {
 	return bigg.PlaylistItem{
 		PlaylistItem: &youtube.PlaylistItem{
+			Id: "PLI-" + videoid,
 			Snippet: &youtube.PlaylistItemSnippet{
 				ResourceId: &youtube.ResourceId{
 					VideoId: videoid,
@@ -301,6 +2023,54 @@ func newChannel(uploadsPl string) bigg.Channel {
 	}
 }
 
+func newVideo(id, duration string, views uint64, live string) bigg.Video {
+	return bigg.Video{
+		Video: &youtube.Video{
+			Id:             id,
+			Snippet:        &youtube.VideoSnippet{LiveBroadcastContent: live},
+			ContentDetails: &youtube.VideoContentDetails{Duration: duration, Definition: "hd"},
+			Statistics:     &youtube.VideoStatistics{ViewCount: views, LikeCount: views / 10},
+		},
+	}
+}
+
+func playlistOf(videoIds ...string) []bigg.PlaylistItem {
+	out := []bigg.PlaylistItem{}
+	for i, v := range videoIds {
+		pli := newPlaylistItem(v, v, "", "", "")
+		pli.Id = fmt.Sprintf("PLI-%s-%d", v, i)
+		pli.Snippet.Position = int64(i)
+		out = append(out, pli)
+	}
+	return out
+}
+
+func playlistVideoIds(f *fakeService, playlistId string) []string {
+	out := []string{}
+	for _, pli := range f.playlistitems[playlistId] {
+		out = append(out, pli.Snippet.ResourceId.VideoId)
+	}
+	return out
+}
+
+// lisLength returns the length of the longest increasing subsequence of s (O(n^2)).
+func lisLength(s []string) int {
+	best := 0
+	l := make([]int, len(s))
+	for i := range s {
+		l[i] = 1
+		for j := 0; j < i; j++ {
+			if s[j] < s[i] && l[j]+1 > l[i] {
+				l[i] = l[j] + 1
+			}
+		}
+		if l[i] > best {
+			best = l[i]
+		}
+	}
+	return best
+}
+
 func sliceContains(s []string, m string) bool {
 	for _, v := range s {
 		// if strings.Contains(v, m) {
@@ -3,6 +3,8 @@ package youtubetoolkit
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
 )
 
 type Item interface {
@@ -11,12 +13,24 @@ type Item interface {
 	AsRecord(fields *[]string) []string
 }
 
+// Status of the Items returned by the flows making changes.
+const (
+	statusAdded   = "added"
+	statusSkipped = "skipped"
+	statusDeleted = "deleted"
+	statusMoved   = "moved"
+	statusOnlyA   = "only-a"
+	statusOnlyB   = "only-b"
+	statusCommon  = "common"
+)
+
 type sub struct {
 	SubscriptionId,
 	ChannelId,
 	ChannelTitle,
 	ChannelUrl,
-	ChannelThumbUrl string `json:",omitempty"`
+	ChannelThumbUrl,
+	Status string `json:",omitempty"`
 }
 
 func (r *sub) AsRecord(fields *[]string) []string {
@@ -25,7 +39,10 @@ func (r *sub) AsRecord(fields *[]string) []string {
 
 type playlist struct {
 	PlaylistId,
-	PlaylistTitle string `json:",omitempty"`
+	PlaylistTitle,
+	Description,
+	PrivacyStatus,
+	PublishedAt string `json:",omitempty"`
 	VideoCount int64 `json:",omitempty"`
 }
 
@@ -41,13 +58,53 @@ type playlistItem struct {
 	VideoId,
 	VideoTitle,
 	VideoUrl,
-	PublishedAt string `json:",omitempty"`
+	PublishedAt,
+	VideoPublishedAt,
+	Status string `json:",omitempty"`
+	Position int64 `json:",omitempty"`
+
+	// video details (see VideoDetails)
+	Duration string `json:",omitempty"`
+	DurationSeconds,
+	ViewCount,
+	LikeCount int64 `json:",omitempty"`
+	LiveBroadcastContent,
+	Definition string `json:",omitempty"`
+
+	// uploads playlist variant the video was listed from (see uploadsVariant)
+	kind VideoKind
+	// has live streaming details: a live stream or a premiere
+	streamed bool
 }
 
 func (r *playlistItem) AsRecord(fields *[]string) []string {
 	return item2record(r, fields)
 }
 
+// videoDetailsFields are the fields set by setVideoDetails.
+var videoDetailsFields = map[string]bool{
+	"Duration": true, "DurationSeconds": true, "ViewCount": true, "LikeCount": true,
+	"LiveBroadcastContent": true, "Definition": true,
+}
+
+func (r *playlistItem) setVideoDetails(v *bigg.Video) {
+	if v.ContentDetails != nil {
+		r.Duration = v.ContentDetails.Duration
+		if d, err := bigg.ParseDuration(v.ContentDetails.Duration); err == nil {
+			r.DurationSeconds = int64(d.Seconds())
+		}
+		r.Definition = v.ContentDetails.Definition
+	}
+	if v.Statistics != nil {
+		r.ViewCount = int64(v.Statistics.ViewCount)
+		r.LikeCount = int64(v.Statistics.LikeCount)
+	}
+	if v.Snippet != nil {
+		r.LiveBroadcastContent = v.Snippet.LiveBroadcastContent
+	}
+	r.streamed = v.LiveStreamingDetails != nil
+}
+
 func item2record(input Item, fields *[]string) []string {
 	out := []string{}
 	elem := reflect.ValueOf(input).Elem()
@@ -0,0 +1,169 @@
+package youtubetoolkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/raffaelecassia/youtubetoolkit/bigg"
+)
+
+// VideoKind is a kind of uploads that can be excluded from the flows (see ExcludeVideos).
+type VideoKind string
+
+const (
+	// VideoShorts are the Shorts, listed in the "UUSH" variant of the uploads playlist.
+	VideoShorts VideoKind = "shorts"
+	// VideoLive are the live streams (current and past), listed in the "UULV" variant.
+	VideoLive VideoKind = "live"
+	// VideoUpcoming are the scheduled live streams and premieres (from the video details).
+	VideoUpcoming VideoKind = "upcoming"
+	// VideoPremiere are the videos published as premieres (long-form videos with live streaming details).
+	VideoPremiere VideoKind = "premiere"
+
+	// the long-form videos, listed in the "UULF" variant
+	videoLongForm VideoKind = "long-form"
+)
+
+// VideoKinds are the kinds of uploads accepted by ExcludeVideos.
+var VideoKinds = []VideoKind{VideoShorts, VideoLive, VideoUpcoming, VideoPremiere}
+
+// ParseVideoKinds parses a comma separated list of VideoKinds (eg. "shorts,live").
+func ParseVideoKinds(s string) ([]VideoKind, error) {
+	out := []VideoKind{}
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		valid := false
+		for _, vk := range VideoKinds {
+			valid = valid || VideoKind(k) == vk
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown video kind %q (valid kinds: shorts, live, upcoming, premiere)", k)
+		}
+		out = append(out, VideoKind(k))
+	}
+	return out, nil
+}
+
+// ExcludeVideos makes LastUploads skip the uploads of the given kinds.
+// Shorts and live streams are skipped listing only the variants of the uploads playlist
+// with the other kinds (1 unit each, instead of 1 for the whole uploads playlist).
+// Upcoming videos and premieres are recognized by the video details (see VideoDetails).
+func ExcludeVideos(kinds ...VideoKind) FlowOption {
+	return func(ic *flowconfig) {
+		if ic.exclude == nil {
+			ic.exclude = map[VideoKind]bool{}
+		}
+		for _, k := range kinds {
+			ic.exclude[k] = true
+		}
+	}
+}
+
+// VideoDuration makes LastUploads skip the videos shorter than min or longer than max
+// (zero means no limit). Durations come from the video details (see VideoDetails).
+func VideoDuration(min, max time.Duration) FlowOption {
+	return func(ic *flowconfig) {
+		ic.minDuration = min
+		ic.maxDuration = max
+	}
+}
+
+// uploadsVariant is a playlist derived from the uploads playlist of a channel ("UU" followed
+// by the channel ID without "UC") listing only a kind of uploads.
+type uploadsVariant struct {
+	prefix string
+	kind   VideoKind
+}
+
+var (
+	allUploads      = uploadsVariant{"UU", ""}
+	longFormUploads = uploadsVariant{"UULF", videoLongForm}
+	shortsUploads   = uploadsVariant{"UUSH", VideoShorts}
+	liveUploads     = uploadsVariant{"UULV", VideoLive}
+)
+
+// playlistId returns the ID of the variant of an uploads playlist.
+// Only the standard uploads playlist IDs ("UU...") have variants.
+func (v uploadsVariant) playlistId(uploadsPlaylistId string) (string, bool) {
+	if v == allUploads {
+		return uploadsPlaylistId, true
+	} else if !strings.HasPrefix(uploadsPlaylistId, "UU") {
+		return "", false
+	}
+	return v.prefix + uploadsPlaylistId[2:], true
+}
+
+// uploadsVariants returns the variants of the uploads playlists to list: the whole uploads
+// playlist, unless some kinds are excluded or the premieres have to be told apart.
+func (ic *flowconfig) uploadsVariants() []uploadsVariant {
+	if !ic.exclude[VideoShorts] && !ic.exclude[VideoLive] && !ic.exclude[VideoPremiere] {
+		return []uploadsVariant{allUploads}
+	}
+	variants := []uploadsVariant{longFormUploads}
+	if !ic.exclude[VideoShorts] {
+		variants = append(variants, shortsUploads)
+	}
+	if !ic.exclude[VideoLive] {
+		variants = append(variants, liveUploads)
+	}
+	return variants
+}
+
+// needsVideoDetails returns true if the video filters need the video details.
+func (ic *flowconfig) needsVideoDetails() bool {
+	return ic.exclude[VideoUpcoming] || ic.exclude[VideoPremiere] || ic.minDuration > 0 || ic.maxDuration > 0
+}
+
+// videoFilter returns the filter of the playlistItem Items (nil if there's nothing to filter).
+// It needs the video details (see needsVideoDetails).
+func (ic *flowconfig) videoFilter() func(*playlistItem) bool {
+	if !ic.needsVideoDetails() {
+		return nil
+	}
+	return func(i *playlistItem) bool {
+		if ic.exclude[VideoUpcoming] && i.LiveBroadcastContent == "upcoming" {
+			return false
+		}
+		if ic.exclude[VideoPremiere] && i.kind == videoLongForm && i.streamed {
+			return false
+		}
+		d := time.Duration(i.DurationSeconds) * time.Second
+		if ic.minDuration > 0 && d < ic.minDuration {
+			return false
+		}
+		if ic.maxDuration > 0 && d > ic.maxDuration {
+			return false
+		}
+		return true
+	}
+}
+
+// filterVideos sends only the playlistItem Items accepted by filter.
+func (tk *Toolkit) filterVideos(ctx context.Context, input <-chan Item, filter func(*playlistItem) bool) <-chan Item {
+	output := make(chan Item, 10)
+	go func() {
+		skipped := 0
+		for i := range input {
+			if pi, ok := i.(*playlistItem); ok && !filter(pi) {
+				skipped++
+				continue
+			}
+			send(ctx, output, i)
+		}
+		if skipped > 0 {
+			tk.log("Videos filtered out:", skipped)
+		}
+		close(output)
+	}()
+	return output
+}
+
+// uploadsCost is the estimated cost of listing the uploads of a channel.
+func uploadsCost(variants []uploadsVariant) uint32 {
+	return uint32(len(variants)) * bigg.QUOTA_COST_LIST
+}
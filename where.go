@@ -0,0 +1,391 @@
+package youtubetoolkit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Expr is a filter expression over the fields of the Items (see ParseExpr).
+type Expr struct {
+	src   string
+	match func(Item) bool
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match returns true if the Item satisfies the expression.
+// Comparisons on fields the Item doesn't have are false.
+func (e *Expr) Match(i Item) bool {
+	return e.match(i)
+}
+
+// Where makes the flow send to the sink only the Items matching the expression.
+// Multiple Where options must all match.
+func Where(e *Expr) FlowOption {
+	return func(ic *flowconfig) {
+		ic.where = append(ic.where, e.Match)
+	}
+}
+
+// ParseExpr parses a filter expression over the fields of the Items of type it, like:
+//
+//	VideoTitle ~ "(?i)podcast" and not ChannelId in file:blocked.txt
+//	PublishedAt > 2024-01-01 or (ViewCount >= 1000 and DurationSeconds < 600)
+//
+// A comparison is a field name (the same of --fields), an operator and a value:
+//   - = != < <= > >= compare numbers for the numeric fields (eg. ViewCount), strings otherwise
+//     (dates like PublishedAt compare as strings, eg. "PublishedAt > 2024-01-01")
+//   - ~ and !~ match a regular expression (https://pkg.go.dev/regexp/syntax)
+//   - in (and not in) a comma separated list of values, or the values in a file (one per line,
+//     or a CSV with the values in the first column) with file:<path>
+//
+// Values with spaces or special chars must be double quoted.
+// Comparisons are combined with and, or, not and parentheses.
+// Unknown fields (not in it), bad regular expressions and unreadable files are parse errors.
+func ParseExpr(s string, it ItemType) (*Expr, error) {
+	tokens, err := lexExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("where %q: %w", s, err)
+	}
+	p := &exprParser{tokens: tokens, itemType: it, fields: it.fields()}
+	match, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("where %q: %w", s, err)
+	}
+	return &Expr{src: s, match: match}, nil
+}
+
+type exprToken struct {
+	kind  byte // 'w' word, 's' quoted string, 'o' operator, or one of "(),"
+	value string
+}
+
+func (t exprToken) String() string {
+	if t.kind == 's' {
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+func lexExpr(s string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, exprToken{c, string(c)})
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			v, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string at %d: %w", i, err)
+			}
+			tokens = append(tokens, exprToken{'s', v})
+			i = j + 1
+		case strings.IndexByte("=!<>~", c) >= 0:
+			op := string(c)
+			if i+1 < len(s) && strings.IndexByte("=~", s[i+1]) >= 0 {
+				op = s[i : i+2]
+			}
+			if !exprOperators[op] {
+				return nil, fmt.Errorf("unknown operator %q at %d", op, i)
+			}
+			i += len(op)
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, exprToken{'o', op})
+		default:
+			j := i
+			for ; j < len(s) && strings.IndexByte(" \t\n\r(),\"=!<>~", s[j]) < 0; j++ {
+			}
+			tokens = append(tokens, exprToken{'w', s[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+var exprOperators = map[string]bool{
+	"=": true, "==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "~": true, "!~": true,
+}
+
+type exprParser struct {
+	tokens   []exprToken
+	pos      int
+	itemType ItemType
+	fields   map[string]bool
+}
+
+func (p *exprParser) peek() (exprToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return exprToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// keyword returns true (consuming it) if the next token is the keyword kw.
+func (p *exprParser) keyword(kw string) bool {
+	if t, ok := p.peek(); ok && t.kind == 'w' && strings.EqualFold(t.value, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) or() (func(Item) bool, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("or") {
+		var right func(Item) bool
+		right, err = p.and()
+		l, r := left, right
+		left = func(i Item) bool { return l(i) || r(i) }
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (func(Item) bool, error) {
+	left, err := p.not()
+	for err == nil && p.keyword("and") {
+		var right func(Item) bool
+		right, err = p.not()
+		l, r := left, right
+		left = func(i Item) bool { return l(i) && r(i) }
+	}
+	return left, err
+}
+
+func (p *exprParser) not() (func(Item) bool, error) {
+	if p.keyword("not") {
+		m, err := p.not()
+		return func(i Item) bool { return !m(i) }, err
+	}
+	if t, ok := p.peek(); ok && t.kind == '(' {
+		p.pos++
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != ')' {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return m, nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (func(Item) bool, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end, want a field")
+	} else if t.kind != 'w' {
+		return nil, fmt.Errorf("unexpected %s, want a field", t)
+	}
+	field := t.value
+	if !p.fields[field] {
+		return nil, fmt.Errorf("unknown field %q (fields: %s)", field, strings.Join(p.itemType.Fields(), ", "))
+	}
+	p.pos++
+
+	negate := p.keyword("not")
+	if p.keyword("in") {
+		values, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return func(i Item) bool {
+			v, ok := fieldValue(i, field)
+			return ok && values[v] != negate
+		}, nil
+	} else if negate {
+		return nil, fmt.Errorf("want in after %s not", field)
+	}
+
+	op, ok := p.peek()
+	if !ok || op.kind != 'o' {
+		return nil, fmt.Errorf("want an operator after %s", field)
+	}
+	p.pos++
+	value, ok := p.peek()
+	if !ok || (value.kind != 'w' && value.kind != 's') {
+		return nil, fmt.Errorf("want a value after %s %s", field, op.value)
+	}
+	p.pos++
+
+	switch op.value {
+	case "~", "!~":
+		re, err := regexp.Compile(value.value)
+		if err != nil {
+			return nil, err
+		}
+		match := op.value == "~"
+		return func(i Item) bool {
+			v, ok := fieldValue(i, field)
+			return ok && re.MatchString(v) == match
+		}, nil
+	}
+	num, numErr := strconv.ParseFloat(value.value, 64)
+	return func(i Item) bool {
+		v, ok := fieldValue(i, field)
+		if !ok {
+			return false
+		}
+		var cmp int
+		if n, err := strconv.ParseFloat(v, 64); err == nil && numErr == nil && isNumericField(i, field) {
+			cmp = compareFloats(n, num)
+		} else {
+			cmp = strings.Compare(v, value.value)
+		}
+		switch op.value {
+		case "=":
+			return cmp == 0
+		case "!=":
+			return cmp != 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default: // ">="
+			return cmp >= 0
+		}
+	}, nil
+}
+
+// list parses the values of in: a comma separated list or file:<path>.
+func (p *exprParser) list() (map[string]bool, error) {
+	values := map[string]bool{}
+	for {
+		t, ok := p.peek()
+		if !ok || (t.kind != 'w' && t.kind != 's') {
+			return nil, fmt.Errorf("want a list of values after in")
+		}
+		p.pos++
+		if t.kind == 'w' && strings.HasPrefix(t.value, "file:") {
+			if err := readListFile(strings.TrimPrefix(t.value, "file:"), values); err != nil {
+				return nil, err
+			}
+		} else {
+			values[t.value] = true
+		}
+		if t, ok := p.peek(); !ok || t.kind != ',' {
+			return values, nil
+		}
+		p.pos++
+	}
+}
+
+// readListFile adds to values the first field of each line of a CSV file.
+func readListFile(file string, values map[string]bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if v := strings.TrimSpace(record[0]); v != "" {
+			values[v] = true
+		}
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ItemType is the type of the Items a flow sends to the sink. The expressions (see ParseExpr)
+// and the sort keys (see ParseSortKeys) of a flow can use only the fields of its ItemType.
+type ItemType int
+
+const (
+	// SubscriptionItem is the Item of Subscriptions, Subscribe, SubscriptionsDiff and SyncSubscriptions.
+	SubscriptionItem ItemType = iota
+	// PlaylistItem is the Item of Playlists.
+	PlaylistItem
+	// PlaylistVideoItem is the Item of the flows on the videos of playlists, like Playlist,
+	// AddVideoToPlaylist and LastUploads.
+	PlaylistVideoItem
+)
+
+// itemTypes are the Items of each ItemType.
+var itemTypes = map[ItemType]Item{SubscriptionItem: &sub{}, PlaylistItem: &playlist{}, PlaylistVideoItem: &playlistItem{}}
+
+// fields returns the exported fields of the Item.
+func (it ItemType) fields() map[string]bool {
+	fields := map[string]bool{}
+	i, ok := itemTypes[it]
+	if !ok {
+		return fields
+	}
+	t := reflect.TypeOf(i).Elem()
+	for n := 0; n < t.NumField(); n++ {
+		if t.Field(n).IsExported() {
+			fields[t.Field(n).Name] = true
+		}
+	}
+	return fields
+}
+
+// Fields returns the sorted names of the fields of the Item.
+func (it ItemType) Fields() []string {
+	names := []string{}
+	for f := range it.fields() {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldValue returns the value of a field of an Item as item2record does,
+// false if the Item doesn't have it.
+func fieldValue(i Item, field string) (string, bool) {
+	f := reflect.ValueOf(i).Elem().FieldByName(field)
+	if !f.IsValid() {
+		return "", false
+	}
+	return item2record(i, &[]string{field})[0], true
+}
+
+func isNumericField(i Item, field string) bool {
+	return reflect.ValueOf(i).Elem().FieldByName(field).CanInt()
+}